@@ -29,21 +29,36 @@ func main() {
 	app.Copyright = "(c) 2024 WitnessChain"
 
 	app.Commands = []*cli.Command{
-		wc_common.KeysCmd(),
+		wc_common.KeysCmd(
+			operator_commands.ListKeysCmd(),
+			operator_commands.RotateKeyCmd(),
+		),
 		operator_commands.RegisterWatchtowerCmd(),
 		operator_commands.DeRegisterWatchtowerCmd(),
 		operator_commands.RegisterOperatorToAVSCmd(),
 		operator_commands.DeRegisterOperatorFromAVSCmd(),
+		operator_commands.SignerCmd(),
+		operator_commands.ConfigCmd(),
+		operator_commands.NetworksCmd(),
 	}
+	wc_common.AddPasswordFlags(app.Commands)
 
-	if err := app.Run(os.Args); err != nil {
-		_, err := fmt.Fprintln(os.Stderr, err)
-		if err != nil {
-			return
-		}
-		wc_common.Unmount()
+	// only main decides the exit code, everything below returns errors
+	if err := run(app); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
+
+// run unmounts the gocryptfs filesystem on every path, including an error
+// or a panic, before main exits. The secrets are wiped by every command
+// once it returns, see AddPasswordFlags
+func run(app *cli.App) (err error) {
+	defer func() {
+		if unmountErr := wc_common.Unmount(); err == nil {
+			err = unmountErr
+		}
+	}()
 
-	wc_common.Unmount()
+	return app.Run(os.Args)
 }
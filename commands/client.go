@@ -0,0 +1,97 @@
+package operator_commands
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+	"github.com/witnesschain-com/operator-cli/pkg/operator"
+)
+
+// NewOperatorClient connects to rpcUrl with the operator key or external
+// signer of config
+func NewOperatorClient(ctx context.Context, config *operator_config.OperatorConfig, rpcUrl string) (*operator.Client, error) {
+	operatorSigner, err := OperatorSigner(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := operator.NewClient(ctx, operator.Config{
+		RPCUrl:           rpcUrl,
+		Operator:         operatorSigner,
+		ExpiryInDays:     config.ExpiryInDays,
+		TxReceiptTimeout: time.Duration(config.TxReceiptTimeout) * time.Second,
+		OnTxSent:         PrintTxSent,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config.ChainID = client.ChainID()
+	fmt.Println("Connection successful : ", config.ChainID)
+	return client, nil
+}
+
+// OperatorSigner returns the signer of the operator key of config, a key
+// that signs in place, a local key or the external signer
+func OperatorSigner(ctx context.Context, config *operator_config.OperatorConfig) (operator.Signer, error) {
+	if config.OperatorKeyName != "" {
+		return keyStoreSigner(ctx, config.KeyType, config.OperatorKeyName, config.OperatorAddress)
+	}
+	return operator.NewVaultSigner(config.OperatorAddress, config.OperatorPrivateKey, config.Endpoint), nil
+}
+
+// WatchtowerSigners returns a signer for every watchtower of config, the
+// signer of each address is the one of its key and the ones without a key
+// use the external signer
+func WatchtowerSigners(ctx context.Context, config *operator_config.OperatorConfig) ([]operator.Signer, error) {
+	privateKeys := make(map[common.Address]*ecdsa.PrivateKey, len(config.WatchtowerPrivateKeys))
+	for _, privateKey := range config.WatchtowerPrivateKeys {
+		privateKeys[crypto.PubkeyToAddress(privateKey.PublicKey)] = privateKey
+	}
+
+	signers := make([]operator.Signer, len(config.WatchtowerAddresses))
+	for i, watchtowerAddress := range config.WatchtowerAddresses {
+		if keyName, ok := config.WatchtowerKeyNames[watchtowerAddress]; ok {
+			signer, err := keyStoreSigner(ctx, config.KeyType, keyName, watchtowerAddress)
+			if err != nil {
+				return nil, err
+			}
+			signers[i] = signer
+		} else if privateKey, ok := privateKeys[watchtowerAddress]; ok {
+			signers[i] = operator.NewVaultSigner(watchtowerAddress, privateKey, config.Endpoint)
+		} else {
+			signers[i] = operator.NewExternalSigner(watchtowerAddress, config.Endpoint)
+		}
+	}
+	return signers, nil
+}
+
+// keyStoreSigner signs with keyName inside the keystore of keyType
+func keyStoreSigner(ctx context.Context, keyType string, keyName string, address common.Address) (operator.Signer, error) {
+	keySigner, ok := wc_common.GetKeySigner(ctx, keyType)
+	if !ok {
+		return nil, fmt.Errorf("Error getting key signer %s: %w", keyType, wc_common.ErrInvalidKeyType)
+	}
+
+	return operator.NewHashSigner(address, func(hash []byte) ([]byte, error) {
+		return keySigner.SignHash(keyName, hash)
+	}), nil
+}
+
+func PrintTxSent(tx operator.TxResult) {
+	fmt.Printf("Tx sent: %s\n", tx.URL)
+}
+
+func PrintTxResult(tx *operator.TxResult) {
+	if tx == nil || tx.Receipt == nil {
+		return
+	}
+	fmt.Println("Transaction executed successfully, logs are ...")
+	fmt.Println(tx.Receipt.Logs)
+}
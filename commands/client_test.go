@@ -0,0 +1,146 @@
+package operator_commands
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+)
+
+// testConfigContext writes config to a config file and returns the
+// context of a command run with it
+func testConfigContext(t *testing.T, config map[string]interface{}) *cli.Context {
+	t.Helper()
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("config-file", configFile, "")
+	set.String("profile", "", "")
+	cCtx := cli.NewContext(nil, set, nil)
+	cCtx.Context = context.Background()
+	return cCtx
+}
+
+func generateKeys(t *testing.T, n int) ([]string, []common.Address) {
+	t.Helper()
+
+	var keys []string
+	var addresses []common.Address
+	for i := 0; i < n; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, common.Bytes2Hex(crypto.FromECDSA(key)))
+		addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	return keys, addresses
+}
+
+func TestWatchtowerSignersWithAddresses(t *testing.T) {
+	keys, addresses := generateKeys(t, 2)
+	cCtx := testConfigContext(t, map[string]interface{}{
+		"operator_address":        "0x0000000000000000000000000000000000000001",
+		"watchtower_addresses":    addresses,
+		"watchtower_private_keys": keys,
+	})
+
+	config, err := operator_config.GetConfigFromContext(cCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.WatchtowerAddresses) != len(addresses) {
+		t.Fatalf("config has %d watchtowers, want %d", len(config.WatchtowerAddresses), len(addresses))
+	}
+
+	signers, err := WatchtowerSigners(cCtx.Context, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != len(addresses) {
+		t.Fatalf("%d signers, want %d", len(signers), len(addresses))
+	}
+	privateKeys := map[common.Address]*ecdsa.PrivateKey{}
+	for _, key := range config.WatchtowerPrivateKeys {
+		privateKeys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	for i, signer := range signers {
+		if signer.Address() != addresses[i] {
+			t.Errorf("signer %d is of %s, want %s", i, signer.Address().Hex(), addresses[i].Hex())
+		}
+		if _, ok := privateKeys[signer.Address()]; !ok {
+			t.Errorf("signer %d has no key", i)
+		}
+	}
+
+	config.Wipe()
+	for _, key := range config.WatchtowerPrivateKeys {
+		if key.D.Sign() != 0 {
+			t.Error("Wipe left a watchtower key")
+		}
+	}
+}
+
+func TestWatchtowerAddressesMismatch(t *testing.T) {
+	keys, addresses := generateKeys(t, 2)
+
+	tests := map[string][]common.Address{
+		"other order":   {addresses[1], addresses[0]},
+		"fewer":         addresses[:1],
+		"more":          {addresses[0], addresses[1], common.HexToAddress("0x0000000000000000000000000000000000000002")},
+		"other address": {addresses[0], common.HexToAddress("0x0000000000000000000000000000000000000002")},
+	}
+	for name, watchtowerAddresses := range tests {
+		cCtx := testConfigContext(t, map[string]interface{}{
+			"operator_address":        "0x0000000000000000000000000000000000000001",
+			"watchtower_addresses":    watchtowerAddresses,
+			"watchtower_private_keys": keys,
+		})
+		if _, err := operator_config.GetConfigFromContext(cCtx); !errors.Is(err, wc_common.ErrAddressMismatch) {
+			t.Errorf("%s: error = %v, want ErrAddressMismatch", name, err)
+		}
+	}
+}
+
+func TestWatchtowerSignersWithoutKeys(t *testing.T) {
+	_, addresses := generateKeys(t, 2)
+	cCtx := testConfigContext(t, map[string]interface{}{
+		"operator_address":     "0x0000000000000000000000000000000000000001",
+		"watchtower_addresses": addresses,
+	})
+
+	config, err := operator_config.GetConfigFromContext(cCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers, err := WatchtowerSigners(cCtx.Context, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != len(addresses) {
+		t.Fatalf("%d signers, want %d", len(signers), len(addresses))
+	}
+	for i, signer := range signers {
+		if signer.Address() != addresses[i] {
+			t.Errorf("signer %d is of %s, want %s", i, signer.Address().Hex(), addresses[i].Hex())
+		}
+	}
+}
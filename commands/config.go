@@ -0,0 +1,95 @@
+package operator_commands
+
+import (
+	"fmt"
+	"os"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ConfigCmd() *cli.Command {
+	var configCmd = &cli.Command{
+		Name:  "config",
+		Usage: "Create and check the operator config file",
+		Subcommands: []*cli.Command{
+			ConfigInitCmd(),
+			ConfigValidateCmd(),
+			ConfigShowCmd(),
+		},
+	}
+	return configCmd
+}
+
+func ConfigValidateCmd() *cli.Command {
+	wc_common.ConfigPathFlag.Value = wc_common.DefaultOpConfig
+	var validateCmd = &cli.Command{
+		Name:      "validate",
+		Usage:     "report every problem of a config file, with its line and field",
+		UsageText: "validate --config-file <configFile> [--profile <profile>] [--strict-secrets]",
+		Flags: []cli.Flag{
+			&wc_common.ConfigPathFlag,
+			&wc_common.ProfileFlag,
+			&wc_common.StrictSecretsFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return ValidateConfigCmd(cCtx)
+		},
+	}
+	return validateCmd
+}
+
+// ValidateConfigCmd prints one line per problem as file:line: field:
+// message, and fails when there is any
+func ValidateConfigCmd(cCtx *cli.Context) error {
+	configFilePath := cCtx.String("config-file")
+
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("Error reading json file: %w", err)
+	}
+
+	problems := operator_config.ValidateConfig(cCtx.Context, data, cCtx.String("profile"), cCtx.Bool(wc_common.StrictSecretsFlag.Name))
+	for _, problem := range problems {
+		if problem.Line == 0 {
+			fmt.Printf("%s: %s\n", configFilePath, problem)
+			continue
+		}
+		fmt.Printf("%s:%s\n", configFilePath, problem)
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%s has %d problems: %w", configFilePath, len(problems), wc_common.ErrInvalidConfig)
+	}
+	fmt.Printf("%s is valid\n", configFilePath)
+	return nil
+}
+
+func ConfigShowCmd() *cli.Command {
+	wc_common.ConfigPathFlag.Value = wc_common.DefaultOpConfig
+	var showCmd = &cli.Command{
+		Name:      "show",
+		Usage:     "print the config a profile results in, with raw keys and rpc credentials hidden",
+		UsageText: "show --config-file <configFile> [--profile <profile>]",
+		Flags: []cli.Flag{
+			&wc_common.ConfigPathFlag,
+			&wc_common.ProfileFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			data, err := operator_config.ReadConfigFile(cCtx.String("config-file"), cCtx.String("profile"))
+			if err != nil {
+				return err
+			}
+
+			data, err = operator_config.HideSecrets(data)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+	return showCmd
+}
@@ -0,0 +1,234 @@
+package operator_commands
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+func ConfigInitCmd() *cli.Command {
+	wc_common.ConfigPathFlag.Value = wc_common.DefaultOpConfig
+	var initCmd = &cli.Command{
+		Name:      "init",
+		Usage:     "write a config file step by step, with keys of the local keystore",
+		UsageText: "init [--config-file <configFile>]",
+		Flags: []cli.Flag{
+			&wc_common.ConfigPathFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return InitConfigCmd(cCtx)
+		},
+	}
+	return initCmd
+}
+
+// InitConfigCmd asks for the network, the RPCs, the keystore type and the
+// keys, and writes the config only once ValidateConfig finds no problem
+func InitConfigCmd(cCtx *cli.Context) error {
+	configFilePath := cCtx.String("config-file")
+	insecure := cCtx.Bool("insecure")
+
+	if _, err := os.Stat(configFilePath); err == nil {
+		if answer := wc_common.PromptLine(fmt.Sprintf("%s already exists, overwrite it? (y/n)", configFilePath)); strings.ToLower(answer) != "y" {
+			return nil
+		}
+	}
+
+	// only key references are set, never raw keys
+	var config operator_config.OperatorConfig
+
+	networks, err := wc_common.GetNetworks()
+	if err != nil {
+		return err
+	}
+
+	l1Networks, proofNetworks := splitNetworks(networks)
+	network, err := chooseOption("Network", l1Networks, 0, networks)
+	if err != nil {
+		return err
+	}
+	if config.EthRPCUrl, err = askRPCUrl("L1", networks[network]); err != nil {
+		return err
+	}
+
+	proofNetwork, err := chooseOption("Proof submission network", append(proofNetworks, "none"), 0, networks)
+	if err != nil {
+		return err
+	}
+	if proofNetwork != "none" {
+		if config.ProofSubmissionRPC, err = askRPCUrl("proof submission", networks[proofNetwork]); err != nil {
+			return err
+		}
+	}
+
+	keyTypes := wc_common.GetKeyStoreTypes()
+	defaultKeyType := sort.SearchStrings(keyTypes, wc_common.KeyTypeW3SecretKey)
+	if config.KeyType, err = chooseOption("Keystore type", keyTypes, defaultKeyType, networks); err != nil {
+		return err
+	}
+	keyStore, err := wc_common.GetKeyStore(cCtx.Context, config.KeyType)
+	if err != nil {
+		return err
+	}
+
+	// only a keystore that was never initialized is initialized here, other
+	// errors like a wrong password are returned
+	keyNames, err := keyStore.KeyNames()
+	if errors.Is(err, wc_common.ErrKeyStoreNotInitialized) {
+		fmt.Printf("The %s keystore is not initialized: %v\n", config.KeyType, err)
+		if err := keyStore.Init(insecure); err != nil {
+			return err
+		}
+		keyNames, err = keyStore.KeyNames()
+	}
+	if err != nil {
+		return err
+	}
+	if len(keyNames) != 0 {
+		fmt.Printf("Keys of the %s keystore: %s\n", config.KeyType, strings.Join(keyNames, ", "))
+	}
+	fmt.Println("Existing keys are used, keys with a new name are created")
+
+	operatorKey := wc_common.PromptLine("Operator key name")
+	if config.OperatorEncryptedKey, err = useOrCreateKey(keyStore, config.KeyType, operatorKey, wc_common.KeyRoleOperator, insecure); err != nil {
+		return err
+	}
+
+	for _, keyName := range strings.Split(wc_common.PromptLine("Watchtower key names, separated by commas"), ",") {
+		if keyName = strings.TrimSpace(keyName); keyName == "" {
+			continue
+		}
+		keyPath, err := useOrCreateKey(keyStore, config.KeyType, keyName, wc_common.KeyRoleWatchtower, insecure)
+		if err != nil {
+			return err
+		}
+		config.WatchtowerEncryptedKeys = append(config.WatchtowerEncryptedKeys, keyPath)
+	}
+
+	data, err := operator_config.MarshalConfig(&config)
+	if err != nil {
+		return fmt.Errorf("Error encoding config file: %w", err)
+	}
+
+	if problems := operator_config.ValidateConfig(cCtx.Context, data, "", true); len(problems) != 0 {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		return fmt.Errorf("%s not written, %d problems: %w", configFilePath, len(problems), wc_common.ErrInvalidConfig)
+	}
+
+	if err := wc_common.WriteFileAtomic(configFilePath, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Written config file: %s\n", configFilePath)
+	return nil
+}
+
+// splitNetworks returns the networks with an AVS directory, where
+// operators register, and the proof submission networks, by chain id
+func splitNetworks(networks map[string]wc_common.ChainConfig) ([]string, []string) {
+	var l1Networks, proofNetworks []string
+	for chainID, network := range networks {
+		if network.AVSDirectoryAddress != (common.Address{}) {
+			l1Networks = append(l1Networks, chainID)
+		} else {
+			proofNetworks = append(proofNetworks, chainID)
+		}
+	}
+	sortChainIDs(l1Networks)
+	sortChainIDs(proofNetworks)
+	return l1Networks, proofNetworks
+}
+
+func sortChainIDs(chainIDs []string) {
+	sort.Slice(chainIDs, func(i, j int) bool {
+		return len(chainIDs[i]) < len(chainIDs[j]) || (len(chainIDs[i]) == len(chainIDs[j]) && chainIDs[i] < chainIDs[j])
+	})
+}
+
+// chooseOption prints numbered options and returns the chosen one, an
+// empty answer takes the option at defaultIndex. Chain ids are printed
+// with their network name
+func chooseOption(desc string, options []string, defaultIndex int, networks map[string]wc_common.ChainConfig) (string, error) {
+	fmt.Printf("%s:\n", desc)
+	for i, option := range options {
+		label := option
+		if network, ok := networks[option]; ok {
+			label = fmt.Sprintf("%s (chain id %s)", network.Name, option)
+		}
+		fmt.Printf("  %d) %s\n", i+1, label)
+	}
+
+	answer := wc_common.PromptLine(fmt.Sprintf("Choose 1-%d [%d]", len(options), defaultIndex+1))
+	if answer == "" {
+		return options[defaultIndex], nil
+	}
+	index, err := strconv.Atoi(answer)
+	if err != nil || index < 1 || index > len(options) {
+		return "", fmt.Errorf("Error reading choice %q: %w", answer, os.ErrInvalid)
+	}
+	return options[index-1], nil
+}
+
+// askRPCUrl asks for an RPC url that answers with the chain id of network,
+// up to three times. An empty answer takes the default RPC of the network
+func askRPCUrl(desc string, network wc_common.ChainConfig) (string, error) {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		rpcUrl := wc_common.PromptLine(fmt.Sprintf("RPC url of the %s chain [%s]", desc, network.RPCUrl))
+		if rpcUrl == "" {
+			rpcUrl = network.RPCUrl
+		}
+
+		var client *ethclient.Client
+		var chainID *big.Int
+		if client, chainID, err = wc_common.ConnectToUrl(rpcUrl); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		client.Close()
+
+		if chainID.Cmp(&network.ChainID) != 0 {
+			err = fmt.Errorf("%s is on chain id %s, not %s: %w", rpcUrl, chainID, network.ChainID.String(), wc_common.ErrChainMismatch)
+			fmt.Println(err)
+			continue
+		}
+		return rpcUrl, nil
+	}
+	return "", err
+}
+
+// useOrCreateKey returns the config reference of keyName, the key is
+// created with role when the keystore does not have it
+func useOrCreateKey(keyStore wc_common.KeyStore, keyType string, keyName string, role string, insecure bool) (string, error) {
+	if err := wc_common.ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	exists, err := wc_common.HasKey(keyStore, keyName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return keyStore.KeyPath(keyName), nil
+	}
+
+	if keyName, err = keyStore.Create(keyName, insecure); err != nil {
+		return "", err
+	}
+	if _, err := wc_common.UpdateKeyMetadata(keyType, keyName, role, nil, nil); err != nil {
+		return "", err
+	}
+	return keyStore.KeyPath(keyName), nil
+}
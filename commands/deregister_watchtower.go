@@ -1,13 +1,10 @@
 package operator_commands
 
 import (
+	"context"
 	"fmt"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/witnesschain-com/diligencewatchtower-client/keystore"
 	wc_common "github.com/witnesschain-com/operator-cli/common"
-	"github.com/witnesschain-com/operator-cli/common/bindings/OperatorRegistry"
 	operator_config "github.com/witnesschain-com/operator-cli/config"
 
 	"github.com/urfave/cli/v2"
@@ -20,15 +17,24 @@ func DeRegisterWatchtowerCmd() *cli.Command {
 		Usage: "De-register the watchtower",
 		Flags: []cli.Flag{
 			&wc_common.ConfigPathFlag,
+			&wc_common.ProfileFlag,
+			&wc_common.StrictSecretsFlag,
 		},
 		Action: func(cCtx *cli.Context) error {
-			config := operator_config.GetConfigFromContext(cCtx)
+			config, err := operator_config.GetConfigFromContext(cCtx)
+			if err != nil {
+				return err
+			}
+			defer config.Wipe()
+
 			if len(config.EthRPCUrl) != 0 {
-				DeRegisterWatchtower(config)
+				if err := DeRegisterWatchtower(cCtx.Context, config); err != nil {
+					return err
+				}
 			}
 			if len(config.ProofSubmissionRPC) != 0 {
 				config.EthRPCUrl = config.ProofSubmissionRPC
-				DeRegisterWatchtower(config)
+				return DeRegisterWatchtower(cCtx.Context, config)
 			}
 			return nil
 		},
@@ -36,40 +42,22 @@ func DeRegisterWatchtowerCmd() *cli.Command {
 	return deregisterWatchtowerCmd
 }
 
-func DeRegisterWatchtower(config *operator_config.OperatorConfig) {
-	var client *ethclient.Client
-	client, config.ChainID = wc_common.ConnectToUrl(config.EthRPCUrl)
-
-	operatorRegistry, err := OperatorRegistry.NewOperatorRegistry(wc_common.NetworkConfig[config.ChainID.String()].OperatorRegistryAddress, client)
-	wc_common.CheckError(err, "Instantiating OperatorRegistry contract failed")
-
-	vc := &keystore.VaultConfig{Address: config.OperatorAddress, ChainID: config.ChainID, PrivateKey: config.OperatorPrivateKey, Endpoint: config.Endpoint}
-	operatorVault, err := keystore.SetupVault(vc)
+// DeRegisterWatchtower deregisters the watchtowers of config on
+// config.EthRPCUrl
+func DeRegisterWatchtower(ctx context.Context, config *operator_config.OperatorConfig) error {
+	client, err := NewOperatorClient(ctx, config, config.EthRPCUrl)
 	if err != nil {
-		wc_common.CheckError(err, "unable to setup vault")
+		return err
 	}
+	defer client.Close()
 
-	if !wc_common.IsOperatorWhitelisted(config.OperatorAddress, operatorRegistry) {
-		fmt.Printf("Operator %s is not whitelisted\n", config.OperatorAddress.Hex())
-		return
+	result, err := client.DeRegisterWatchtowers(ctx, config.WatchtowerAddresses)
+	for _, watchtowerAddress := range result.Skipped {
+		fmt.Printf("Watchtower %s is already deRegistered\n", watchtowerAddress.Hex())
 	}
-
-	transactOpts := operatorVault.NewTransactOpts(config.ChainID)
-
-	if (wc_common.NetworkConfig[config.ChainID.String()].GasPrice == -1){
-		transactOpts.GasPrice = big.NewInt(0)
-	}
-
-	for _, watchtowerAddress := range config.WatchtowerAddresses {
-		fmt.Println("Deregister watchtower: " + watchtowerAddress.Hex())
-		if !wc_common.IsWatchtowerRegistered(watchtowerAddress, operatorRegistry) {
-			fmt.Printf("Watchtower %s is already deRegistered\n", watchtowerAddress.Hex())
-			continue
-		}
-
-		regTx, err := operatorRegistry.DeRegister(transactOpts, watchtowerAddress)
-		wc_common.CheckError(err, "Registering watchtower as operator failed")
-		fmt.Printf("Tx sent: %s/tx/%s\n", wc_common.NetworkConfig[config.ChainID.String()].BlockExplorer, regTx.Hash().Hex())
-		wc_common.WaitForTransactionReceipt(client, regTx, config.TxReceiptTimeout)
+	for _, tx := range result.Transactions {
+		fmt.Printf("Deregistered watchtower %s\n", tx.Watchtower.Hex())
+		PrintTxResult(&tx.TxResult)
 	}
+	return err
 }
@@ -0,0 +1,166 @@
+package operator_commands
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+	"github.com/witnesschain-com/operator-cli/pkg/operator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// KeyStatus is the registration state of one stored key on one chain
+type KeyStatus struct {
+	KeyName       string
+	Address       common.Address
+	ChainID       *big.Int
+	Watchtower    bool
+	Owner         common.Address
+	Operator      bool
+	AVSRegistered bool
+	HasAVS        bool
+}
+
+// ListKeysCmd is the keys list command of the common package with the chain
+// aware --status flag added
+func ListKeysCmd() *cli.Command {
+	wc_common.ConfigPathFlag.Value = wc_common.DefaultOpConfig
+	listCmd := wc_common.ListCmd()
+	listCmd.UsageText = "list [--status --config-file <configFile> [--profile <profile>]]"
+	listCmd.Flags = append(listCmd.Flags, &wc_common.StatusFlag, &wc_common.ConfigPathFlag, &wc_common.ProfileFlag, &wc_common.StrictSecretsFlag)
+
+	listKeys := listCmd.Action
+	listCmd.Action = func(cCtx *cli.Context) error {
+		if !cCtx.Bool("status") {
+			return listKeys(cCtx)
+		}
+
+		// only the RPCs and the operator address are read, no key of the
+		// config is decrypted
+		config, err := operator_config.GetRPCConfigFromContext(cCtx)
+		if err != nil {
+			return err
+		}
+		defer config.Wipe()
+		operatorAddress, err := operator_config.GetOperatorAddress(cCtx.Context, config)
+		if err != nil {
+			return err
+		}
+		keyType := config.KeyType
+		if cCtx.IsSet("key-type") {
+			keyType = cCtx.String("key-type")
+		}
+
+		statuses, err := GetKeyStatus(cCtx.Context, config, keyType)
+		if err != nil {
+			return err
+		}
+		PrintKeyStatus(statuses, operatorAddress)
+		return nil
+	}
+	return listCmd
+}
+
+// GetKeyStatus queries the registration of every key of the keystore on
+// every RPC of the config. Addresses come from the key metadata when it is
+// recorded, so only keys without metadata are decrypted
+func GetKeyStatus(ctx context.Context, config *operator_config.OperatorConfig, keyType string) ([]KeyStatus, error) {
+	keyStore, err := wc_common.GetKeyStore(ctx, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	keyNames, err := keyStore.KeyNames()
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]common.Address, len(keyNames))
+	for i, keyName := range keyNames {
+		if addresses[i], err = wc_common.GetKeyAddress(keyStore, keyType, keyName); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []KeyStatus
+	for _, rpcUrl := range []string{config.EthRPCUrl, config.ProofSubmissionRPC} {
+		if len(rpcUrl) == 0 {
+			continue
+		}
+
+		chainStatuses, err := getChainKeyStatus(ctx, rpcUrl, keyNames, addresses)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, chainStatuses...)
+	}
+
+	return statuses, nil
+}
+
+func getChainKeyStatus(ctx context.Context, rpcUrl string, keyNames []string, addresses []common.Address) ([]KeyStatus, error) {
+	client, err := operator.NewReadOnlyClient(ctx, rpcUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var statuses []KeyStatus
+	for i, keyName := range keyNames {
+		status := KeyStatus{KeyName: keyName, Address: addresses[i], ChainID: client.ChainID()}
+
+		if status.Watchtower, err = client.IsWatchtowerRegistered(ctx, status.Address); err != nil {
+			return nil, err
+		}
+		if status.Watchtower {
+			if status.Owner, err = client.WatchtowerOperator(ctx, status.Address); err != nil {
+				return nil, err
+			}
+		}
+
+		if status.Operator, err = client.IsOperatorWhitelisted(ctx, status.Address); err != nil {
+			return nil, err
+		}
+		if client.HasAVS() {
+			status.HasAVS = true
+			if status.AVSRegistered, err = client.IsOperatorRegistered(ctx, status.Address); err != nil {
+				return nil, err
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// PrintKeyStatus prints one row per key and chain. A watchtower owned by an
+// operator other than the one of the config is marked foreign, a key that
+// is registered nowhere is marked unregistered
+func PrintKeyStatus(statuses []KeyStatus, operator common.Address) {
+	fmt.Printf("   %-30s %-42s %-12s %-14s %-42s %-6s\n", "Name", "Address", "Chain", "Status", "Operator", "AVS")
+	for _, status := range statuses {
+		state := "unregistered"
+		owner := "-"
+		switch {
+		case status.Watchtower && status.Owner != operator:
+			state = "foreign"
+			owner = status.Owner.Hex()
+		case status.Watchtower:
+			state = "watchtower"
+			owner = status.Owner.Hex()
+		case status.Operator || status.AVSRegistered:
+			state = "operator"
+		}
+
+		avs := "-"
+		if status.HasAVS {
+			avs = fmt.Sprint(status.AVSRegistered)
+		}
+
+		fmt.Printf("   %-30s %-42s %-12s %-14s %-42s %-6s\n", status.KeyName, status.Address.Hex(), status.ChainID.String(), state, owner, avs)
+	}
+}
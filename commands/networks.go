@@ -0,0 +1,180 @@
+package operator_commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+
+	"github.com/urfave/cli/v2"
+)
+
+func NetworksCmd() *cli.Command {
+	var networksCmd = &cli.Command{
+		Name:  "networks",
+		Usage: "Manage the networks witnesschain contracts are deployed on",
+		Subcommands: []*cli.Command{
+			NetworksListCmd(),
+			NetworksShowCmd(),
+			NetworksAddCmd(),
+			NetworksRemoveCmd(),
+		},
+	}
+	return networksCmd
+}
+
+func NetworksListCmd() *cli.Command {
+	var listCmd = &cli.Command{
+		Name:      "list",
+		Usage:     "list the built-in networks and the networks of the networks file",
+		UsageText: "list",
+		Action: func(cCtx *cli.Context) error {
+			networks, err := wc_common.ListNetworks()
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Networks file : %s\n", wc_common.GetNetworksFile())
+			fmt.Printf("   %-22s %-12s %-10s %-42s %s\n", "Name", "Chain", "Source", "OperatorRegistry", "RPC")
+			for _, network := range networks {
+				fmt.Printf("   %-22s %-12s %-10s %-42s %s\n", network.Name, network.ChainID.String(), network.Source, network.OperatorRegistryAddress.Hex(), network.RPCUrl)
+			}
+			return nil
+		},
+	}
+	return listCmd
+}
+
+func NetworksShowCmd() *cli.Command {
+	var showCmd = &cli.Command{
+		Name:      "show",
+		Usage:     "show the contract addresses of a network",
+		UsageText: "show --network <name|chainId> [--json]",
+		Flags: []cli.Flag{
+			&wc_common.NetworkFlag,
+			&wc_common.JSONFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			network, err := wc_common.FindNetwork(cCtx.String("network"))
+			if err != nil {
+				return err
+			}
+
+			if cCtx.Bool("json") {
+				data, err := json.MarshalIndent(&network.ChainConfig, "", "  ")
+				if err != nil {
+					return fmt.Errorf("Error encoding network: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Println("Name                    : ", network.Name)
+			fmt.Println("Chain id                : ", network.ChainID.String())
+			fmt.Println("Source                  : ", network.Source)
+			fmt.Println("RPC                     : ", network.RPCUrl)
+			fmt.Println("OperatorRegistry        : ", network.OperatorRegistryAddress.Hex())
+			fmt.Println("WitnessHub              : ", network.WitnessHubAddress.Hex())
+			fmt.Println("AVSDirectory            : ", network.AVSDirectoryAddress.Hex())
+			fmt.Println("DiligenceProofManager   : ", network.DiligenceProofManagerAddress.Hex())
+			fmt.Println("Block explorer          : ", network.BlockExplorer)
+			fmt.Println("Gas price               : ", network.GasPrice)
+			return nil
+		},
+	}
+	return showCmd
+}
+
+func NetworksAddCmd() *cli.Command {
+	var addCmd = &cli.Command{
+		Name:      "add",
+		Usage:     "add a network, or override a network with the same chain id",
+		UsageText: "add --name <name> --chain-id <chainId> [--copy-from <name|chainId>] [--rpc-url <url>] [--operator-registry <address>] ...",
+		Flags: []cli.Flag{
+			&wc_common.NetworkNameFlag,
+			&wc_common.ChainIDFlag,
+			&wc_common.CopyNetworkFlag,
+			&wc_common.RPCUrlFlag,
+			&wc_common.OperatorRegistryFlag,
+			&wc_common.WitnessHubFlag,
+			&wc_common.AVSDirectoryFlag,
+			&wc_common.DiligenceProofManagerFlag,
+			&wc_common.BlockExplorerFlag,
+			&wc_common.GasPriceFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return AddNetworkCmd(cCtx)
+		},
+	}
+	return addCmd
+}
+
+// AddNetworkCmd builds the network from --copy-from, when set, and the
+// flags given, and writes it to the networks file
+func AddNetworkCmd(cCtx *cli.Context) error {
+	var chainConfig wc_common.ChainConfig
+	if cCtx.String("copy-from") != "" {
+		network, err := wc_common.FindNetwork(cCtx.String("copy-from"))
+		if err != nil {
+			return err
+		}
+		chainConfig = network.ChainConfig
+	}
+
+	chainConfig.Name = cCtx.String("name")
+	chainConfig.ChainID = *new(big.Int).SetUint64(cCtx.Uint64("chain-id"))
+
+	if cCtx.IsSet("rpc-url") {
+		chainConfig.RPCUrl = cCtx.String("rpc-url")
+	}
+	if cCtx.IsSet("block-explorer") {
+		chainConfig.BlockExplorer = cCtx.String("block-explorer")
+	}
+	if cCtx.IsSet("gas-price") {
+		chainConfig.GasPrice = cCtx.Int("gas-price")
+	}
+
+	addresses := map[string]*common.Address{
+		"operator-registry":       &chainConfig.OperatorRegistryAddress,
+		"witness-hub":             &chainConfig.WitnessHubAddress,
+		"avs-directory":           &chainConfig.AVSDirectoryAddress,
+		"diligence-proof-manager": &chainConfig.DiligenceProofManagerAddress,
+	}
+	for flag, address := range addresses {
+		if !cCtx.IsSet(flag) {
+			continue
+		}
+		if !common.IsHexAddress(cCtx.String(flag)) {
+			return fmt.Errorf("--%s %s is not an address: %w", flag, cCtx.String(flag), wc_common.ErrInvalidNetwork)
+		}
+		*address = common.HexToAddress(cCtx.String(flag))
+	}
+
+	if err := wc_common.AddNetwork(chainConfig); err != nil {
+		return err
+	}
+	fmt.Printf("Added network: %s %s\n", chainConfig.Name, chainConfig.ChainID.String())
+	return nil
+}
+
+func NetworksRemoveCmd() *cli.Command {
+	var removeCmd = &cli.Command{
+		Name:      "remove",
+		Usage:     "remove a network of the networks file",
+		UsageText: "remove --network <name|chainId>",
+		Flags: []cli.Flag{
+			&wc_common.NetworkFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			network, err := wc_common.RemoveNetwork(cCtx.String("network"))
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed network: %s %s\n", network.Name, network.ChainID.String())
+			return nil
+		},
+	}
+	return removeCmd
+}
@@ -1,14 +1,10 @@
 package operator_commands
 
 import (
-	"crypto/ecdsa"
+	"context"
 	"fmt"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/witnesschain-com/diligencewatchtower-client/keystore"
 	wc_common "github.com/witnesschain-com/operator-cli/common"
-	"github.com/witnesschain-com/operator-cli/common/bindings/OperatorRegistry"
 	operator_config "github.com/witnesschain-com/operator-cli/config"
 
 	"github.com/urfave/cli/v2"
@@ -21,18 +17,27 @@ func RegisterWatchtowerCmd() *cli.Command {
 		Usage: "Register a watchtower",
 		Flags: []cli.Flag{
 			&wc_common.ConfigPathFlag,
+			&wc_common.ProfileFlag,
+			&wc_common.StrictSecretsFlag,
 		},
 		Action: func(cCtx *cli.Context) error {
-			config := operator_config.GetConfigFromContext(cCtx)
+			config, err := operator_config.GetConfigFromContext(cCtx)
+			if err != nil {
+				return err
+			}
+			defer config.Wipe()
+
 			if len(config.EthRPCUrl) != 0 {
 				// register on L1
-				RegisterWatchtower(config)
+				if err := RegisterWatchtower(cCtx.Context, config); err != nil {
+					return err
+				}
 			}
 
 			if len(config.ProofSubmissionRPC) != 0 {
 				// register on Proof submission chain
 				config.EthRPCUrl = config.ProofSubmissionRPC
-				RegisterWatchtower(config)
+				return RegisterWatchtower(cCtx.Context, config)
 			}
 			return nil
 		},
@@ -40,54 +45,26 @@ func RegisterWatchtowerCmd() *cli.Command {
 	return registerWatchtowerCmd
 }
 
-func RegisterWatchtower(config *operator_config.OperatorConfig) {
-	var client *ethclient.Client
-	client, config.ChainID = wc_common.ConnectToUrl(config.EthRPCUrl)
-
-	operatorRegistry, err := OperatorRegistry.NewOperatorRegistry(wc_common.NetworkConfig[config.ChainID.String()].OperatorRegistryAddress, client)
-	wc_common.CheckError(err, "Instantiating OperatorRegistry contract failed")
-
-	vc := &keystore.VaultConfig{Address: config.OperatorAddress, ChainID: config.ChainID, PrivateKey: config.OperatorPrivateKey, Endpoint: config.Endpoint}
-	operatorVault, err := keystore.SetupVault(vc)
+// RegisterWatchtower registers the watchtowers of config on config.EthRPCUrl
+func RegisterWatchtower(ctx context.Context, config *operator_config.OperatorConfig) error {
+	client, err := NewOperatorClient(ctx, config, config.EthRPCUrl)
 	if err != nil {
-		wc_common.CheckError(err, "unable to setup vault")
+		return err
 	}
+	defer client.Close()
 
-	if !wc_common.IsOperatorWhitelisted(config.OperatorAddress, operatorRegistry) {
-		fmt.Printf("Operator %s is not whitelisted\n", config.OperatorAddress.Hex())
-		return
+	watchtowers, err := WatchtowerSigners(ctx, config)
+	if err != nil {
+		return err
 	}
 
-	transactOpts := operatorVault.NewTransactOpts(config.ChainID)
-
-	if (wc_common.NetworkConfig[config.ChainID.String()].GasPrice == -1){
-		transactOpts.GasPrice = big.NewInt(0)
+	result, err := client.RegisterWatchtowers(ctx, watchtowers)
+	for _, watchtowerAddress := range result.Skipped {
+		fmt.Printf("Watchtower %s is already registered\n", watchtowerAddress.Hex())
 	}
-
-	expiry := wc_common.CalculateExpiry(client, config.ExpiryInDays)
-
-	for i, watchtowerAddress := range config.WatchtowerAddresses {
-		fmt.Println("watchtowerAddress: " + watchtowerAddress.Hex())
-
-		var watchtowerPrivateKey *ecdsa.PrivateKey
-		if len(config.WatchtowerPrivateKeys) != 0 {
-			watchtowerPrivateKey = config.WatchtowerPrivateKeys[i]
-		}
-
-		vc := &keystore.VaultConfig{Address: watchtowerAddress, ChainID: config.ChainID, PrivateKey: watchtowerPrivateKey, Endpoint: config.Endpoint}
-		watchtowerVault, err := keystore.SetupVault(vc)
-		wc_common.CheckError(err, "unable to setup watchtower vault")
-
-		if wc_common.IsWatchtowerRegistered(watchtowerAddress, operatorRegistry) {
-			fmt.Printf("Watchtower %s is already registered\n", watchtowerAddress.Hex())
-			continue
-		}
-
-		salt := wc_common.GenerateSalt()
-		signedMessage := SignOperatorAddress(client, operatorRegistry, watchtowerVault, config.OperatorAddress, salt, expiry)
-		regTx, err := operatorRegistry.RegisterWatchtowerAsOperator(transactOpts, watchtowerAddress, salt, expiry, signedMessage)
-		wc_common.CheckError(err, "Registering watchtower as operator failed")
-		fmt.Printf("Tx sent: %s/tx/%s\n", wc_common.NetworkConfig[config.ChainID.String()].BlockExplorer, regTx.Hash().Hex())
-		wc_common.WaitForTransactionReceipt(client, regTx, config.TxReceiptTimeout)
+	for _, tx := range result.Transactions {
+		fmt.Printf("Registered watchtower %s\n", tx.Watchtower.Hex())
+		PrintTxResult(&tx.TxResult)
 	}
+	return err
 }
@@ -0,0 +1,216 @@
+package operator_commands
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+	"github.com/witnesschain-com/operator-cli/pkg/operator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// RotationResult is the outcome of a key rotation on one chain
+type RotationResult struct {
+	RPCUrl       string
+	ChainID      *big.Int
+	Registered   bool
+	Deregistered bool
+}
+
+func RotateKeyCmd() *cli.Command {
+	wc_common.ConfigPathFlag.Value = wc_common.DefaultOpConfig
+	var rotateKeyCmd = &cli.Command{
+		Name:      "rotate",
+		Usage:     "replace a watchtower key and its registration on every configured chain",
+		UsageText: "rotate --key-name <keyName> --config-file <configFile> [--profile <profile>]",
+		Flags: []cli.Flag{
+			&wc_common.KeyNameFlag,
+			&wc_common.ConfigPathFlag,
+			&wc_common.ProfileFlag,
+			&wc_common.StrictSecretsFlag,
+			&wc_common.InsecureFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.String("key-name") == "" {
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", wc_common.ErrEmptyKeyName)
+			}
+			config, err := operator_config.GetConfigFromContext(cCtx)
+			if err != nil {
+				return err
+			}
+			defer config.Wipe()
+			return RotateWatchtowerKey(cCtx.Context, config, cCtx.String("key-name"), cCtx.Bool("insecure"))
+		},
+	}
+	return rotateKeyCmd
+}
+
+// RotateWatchtowerKey registers a new key for the watchtower keyName on every
+// chain of the config and deregisters the old address once the new one is
+// registered. The new key is stored as a pending key before any transaction
+// is sent, so an interrupted rotation is resumed by running it again
+func RotateWatchtowerKey(ctx context.Context, config *operator_config.OperatorConfig, keyName string, insecure bool) error {
+	if err := wc_common.ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	// the old key is archived and the new one generated and used here, keys
+	// that only sign inside their keystore can't be
+	if _, signInPlace := wc_common.GetKeySigner(ctx, config.KeyType); signInPlace {
+		return fmt.Errorf("Error rotating %s, rotation is not supported for %s keys, create a new key and register it instead: %w", keyName, config.KeyType, wc_common.ErrNotSupported)
+	}
+
+	keyStore, err := wc_common.GetKeyStore(ctx, config.KeyType)
+	if err != nil {
+		return err
+	}
+
+	oldKey, err := keyStore.Load(keyName)
+	if err != nil {
+		return err
+	}
+	defer wc_common.WipePrivateKey(oldKey)
+	oldAddress := crypto.PubkeyToAddress(oldKey.PublicKey)
+
+	pendingKeyName := keyName + wc_common.RotatingKeySuffix
+	newKey, err := loadPendingKey(keyStore, pendingKeyName, insecure)
+	if err != nil {
+		return err
+	}
+	defer wc_common.WipePrivateKey(newKey)
+	newAddress := crypto.PubkeyToAddress(newKey.PublicKey)
+
+	fmt.Printf("Rotating watchtower %s -> %s\n", oldAddress.Hex(), newAddress.Hex())
+
+	var results []RotationResult
+	for _, rpcUrl := range []string{config.EthRPCUrl, config.ProofSubmissionRPC} {
+		if len(rpcUrl) == 0 {
+			continue
+		}
+		result, err := rotateWatchtowerOnChain(ctx, config, rpcUrl, oldAddress, newKey)
+		if err != nil {
+			return fmt.Errorf("Rotation incomplete, the new key is kept as %s. Run rotate again to resume: %w", pendingKeyName, err)
+		}
+		results = append(results, result)
+	}
+
+	PrintRotationResults(results)
+
+	for _, result := range results {
+		if !result.Registered || !result.Deregistered {
+			fmt.Printf("Rotation incomplete, the new key is kept as %s. Run rotate again to resume\n", pendingKeyName)
+			return nil
+		}
+	}
+
+	archivedKeyName := fmt.Sprintf("%s%s%d", keyName, wc_common.ArchivedKeySuffix, time.Now().Unix())
+	if err := keyStore.Save(archivedKeyName, oldKey, insecure); err != nil {
+		return err
+	}
+	if err := keyStore.Save(keyName, newKey, insecure); err != nil {
+		return err
+	}
+	if err := keyStore.Delete(pendingKeyName); err != nil {
+		return err
+	}
+
+	if err := rotateKeyMetadata(config.KeyType, keyName, archivedKeyName, newAddress); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rotated key: %s\n", keyName)
+	fmt.Printf("Old key archived as: %s\n", archivedKeyName)
+	return nil
+}
+
+// loadPendingKey returns the key of an interrupted rotation, or stores a
+// new one under pendingKeyName
+func loadPendingKey(keyStore wc_common.KeyStore, pendingKeyName string, insecure bool) (*ecdsa.PrivateKey, error) {
+	pending, err := wc_common.HasKey(keyStore, pendingKeyName)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		fmt.Printf("Resuming rotation with pending key: %s\n", pendingKeyName)
+		return keyStore.Load(pendingKeyName)
+	}
+
+	newKey, err := wc_common.GenerateRandomKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := keyStore.Save(pendingKeyName, newKey, insecure); err != nil {
+		wc_common.WipePrivateKey(newKey)
+		return nil, err
+	}
+	return newKey, nil
+}
+
+// rotateKeyMetadata moves the metadata of keyName to the archived key, the
+// new key takes over the role and labels of the old one
+func rotateKeyMetadata(keyType string, keyName string, archivedKeyName string, newAddress common.Address) error {
+	metadata, ok, err := wc_common.GetKeyMetadata(keyType, keyName)
+	if err != nil {
+		return err
+	}
+	if err := wc_common.CopyKeyMetadata(keyType, keyName, keyType, archivedKeyName); err != nil {
+		return err
+	}
+	if err := wc_common.RecordKeyMetadata(keyType, keyName, newAddress, wc_common.KeySourceRotated); err != nil {
+		return err
+	}
+
+	role := wc_common.KeyRoleWatchtower
+	if ok && metadata.Role != "" {
+		role = metadata.Role
+	}
+	_, err = wc_common.UpdateKeyMetadata(keyType, keyName, role, metadata.Labels, nil)
+	return err
+}
+
+func rotateWatchtowerOnChain(ctx context.Context, config *operator_config.OperatorConfig, rpcUrl string, oldAddress common.Address, newKey *ecdsa.PrivateKey) (RotationResult, error) {
+	client, err := NewOperatorClient(ctx, config, rpcUrl)
+	if err != nil {
+		return RotationResult{}, err
+	}
+	defer client.Close()
+
+	result := RotationResult{RPCUrl: rpcUrl, ChainID: client.ChainID()}
+
+	newSigner := operator.NewVaultSigner(crypto.PubkeyToAddress(newKey.PublicKey), newKey, config.Endpoint)
+	if _, err := client.RegisterWatchtowers(ctx, []operator.Signer{newSigner}); err != nil {
+		return result, err
+	}
+
+	// only drop the old address once the new one is mined, otherwise the
+	// watchtower would be left without a registered key on this chain
+	registered, err := client.IsWatchtowerRegistered(ctx, newSigner.Address())
+	if err != nil || !registered {
+		return result, err
+	}
+	result.Registered = true
+
+	if _, err := client.DeRegisterWatchtowers(ctx, []common.Address{oldAddress}); err != nil {
+		return result, err
+	}
+
+	registered, err = client.IsWatchtowerRegistered(ctx, oldAddress)
+	result.Deregistered = err == nil && !registered
+	return result, err
+}
+
+// PrintRotationResults prints the RPCs with their path and query hidden,
+// as they often hold an API key
+func PrintRotationResults(results []RotationResult) {
+	fmt.Printf("   %-12s %-60s %-12s %-12s\n", "Chain", "RPC", "Registered", "Deregistered")
+	for _, result := range results {
+		fmt.Printf("   %-12s %-60s %-12v %-12v\n", result.ChainID.String(), operator_config.HideUrl(result.RPCUrl), result.Registered, result.Deregistered)
+	}
+}
@@ -0,0 +1,21 @@
+package operator_commands
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	operator_config "github.com/witnesschain-com/operator-cli/config"
+)
+
+func TestRotateSignInPlaceKey(t *testing.T) {
+	ctx := wc_common.WithKeyStores(context.Background(), wc_common.NewKeyStores(nil))
+
+	for _, keyType := range []string{wc_common.KeyTypePKCS11, wc_common.KeyTypeVaultTransit} {
+		config := &operator_config.OperatorConfig{KeyType: keyType}
+		if err := RotateWatchtowerKey(ctx, config, "watchtower1", true); !errors.Is(err, wc_common.ErrNotSupported) {
+			t.Errorf("rotating a %s key error = %v, want ErrNotSupported", keyType, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package operator_commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	"github.com/witnesschain-com/operator-cli/pkg/signer"
+
+	"github.com/urfave/cli/v2"
+)
+
+func SignerCmd() *cli.Command {
+	var signerCmd = &cli.Command{
+		Name:  "signer",
+		Usage: "Sign for watchtower hosts and the cli without sharing the key files",
+		Subcommands: []*cli.Command{
+			SignerServeCmd(),
+		},
+	}
+	return signerCmd
+}
+
+func SignerServeCmd() *cli.Command {
+	var serveCmd = &cli.Command{
+		Name:      "serve",
+		Usage:     "serve keys of the local keystore over the clef and Web3Signer apis",
+		UsageText: "serve --key-name <keyName> [--allowlist <file>] [--rpc-url <url>] [--unix-socket <path> | --listen <127.0.0.1:port> --tls-cert <file> --tls-key <file>]",
+		Flags: []cli.Flag{
+			&wc_common.KeyNamesFlag,
+			&wc_common.KeyStoreType,
+			&wc_common.AllowlistFlag,
+			&wc_common.UnixSocketFlag,
+			&wc_common.ListenFlag,
+			&wc_common.TLSCertFlag,
+			&wc_common.TLSKeyFlag,
+			&wc_common.AuditLogFlag,
+			&wc_common.SignerRPCUrlsFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return ServeSignerCmd(cCtx)
+		},
+	}
+	return serveCmd
+}
+
+// ServeSignerCmd loads the keys of --key-name and of the allowlist, and
+// serves them until interrupted. Keys that are not in the allowlist get
+// the default policy. Encoded EIP-712 messages are signed for the chains of
+// --rpc-url
+func ServeSignerCmd(cCtx *cli.Context) error {
+	policies := map[string]signer.Policy{}
+	if cCtx.String("allowlist") != "" {
+		var err error
+		if policies, err = signer.LoadAllowlist(cCtx.String("allowlist")); err != nil {
+			return err
+		}
+	}
+
+	keyNames := signerKeyNames(cCtx.StringSlice("key-name"), policies)
+	if len(keyNames) == 0 {
+		return fmt.Errorf("Required flag \"key-name\" or \"allowlist\" not set: %w", wc_common.ErrEmptyKeyName)
+	}
+
+	keyStore, err := wc_common.GetKeyStore(cCtx.Context, cCtx.String("key-type"))
+	if err != nil {
+		return err
+	}
+
+	var keys []*signer.Key
+	for _, keyName := range keyNames {
+		if err := wc_common.ValidateKeyName(keyName); err != nil {
+			return fmt.Errorf("Error validating key name: %w", err)
+		}
+
+		privateKey, err := keyStore.Load(keyName)
+		if err != nil {
+			return err
+		}
+		defer wc_common.WipePrivateKey(privateKey)
+
+		policy, ok := policies[keyName]
+		if !ok {
+			if policy, err = signer.DefaultPolicy(); err != nil {
+				return err
+			}
+		}
+		keys = append(keys, &signer.Key{Name: keyName, PrivateKey: privateKey, Policy: policy})
+	}
+
+	if err := wc_common.EnsureDirectory(filepath.Dir(cCtx.String("audit-log"))); err != nil {
+		return err
+	}
+	auditFile, err := os.OpenFile(cCtx.String("audit-log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Error opening audit log: %w", err)
+	}
+	defer auditFile.Close()
+
+	var chains []signer.Chain
+	for _, rpcUrl := range cCtx.StringSlice("rpc-url") {
+		client, chainID, err := wc_common.ConnectToUrl(rpcUrl)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		chainConfig, err := wc_common.GetChainConfig(chainID)
+		if err != nil {
+			return err
+		}
+		chains = append(chains, signer.Chain{Config: chainConfig, Caller: client})
+	}
+
+	server, err := signer.NewServer(keys, chains, signer.NewAuditLog(auditFile))
+	if err != nil {
+		return err
+	}
+
+	listener, err := signerListener(cCtx)
+	if err != nil {
+		return err
+	}
+
+	for i, address := range server.Addresses() {
+		fmt.Printf("Serving key: %s %s\n", keys[i].Name, address.Hex())
+	}
+	fmt.Printf("Signer listening on %s\n", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(cCtx.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Serve(ctx, listener)
+}
+
+func signerKeyNames(keyNames []string, policies map[string]signer.Policy) []string {
+	names := map[string]bool{}
+	for _, keyName := range keyNames {
+		names[keyName] = true
+	}
+	for keyName := range policies {
+		names[keyName] = true
+	}
+
+	var sorted []string
+	for keyName := range names {
+		sorted = append(sorted, keyName)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// signerListener listens on the unix socket, readable by the user only, or
+// with tls on a loopback address
+func signerListener(cCtx *cli.Context) (net.Listener, error) {
+	if address := cCtx.String("listen"); address != "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading listen address: %w", err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return nil, fmt.Errorf("%s: %w", address, wc_common.ErrInsecureListenAddress)
+		}
+		if cCtx.String("tls-cert") == "" || cCtx.String("tls-key") == "" {
+			return nil, fmt.Errorf("Required flags \"tls-cert\" and \"tls-key\" not set: %w", wc_common.ErrInsecureListenAddress)
+		}
+
+		certificate, err := tls.LoadX509KeyPair(cCtx.String("tls-cert"), cCtx.String("tls-key"))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading tls certificate: %w", err)
+		}
+
+		listener, err := tls.Listen("tcp", address, &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return nil, fmt.Errorf("Error listening on %s: %w", address, err)
+		}
+		return listener, nil
+	}
+
+	socketPath := cCtx.String("unix-socket")
+	if err := wc_common.EnsureDirectory(filepath.Dir(socketPath)); err != nil {
+		return nil, err
+	}
+
+	// a socket left by a signer that was killed
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error listening on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("Error restricting the socket permissions: %w", err)
+	}
+	return listener, nil
+}
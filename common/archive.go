@@ -0,0 +1,278 @@
+package wc_common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+// KeyArchive is the decrypted content of a keystore archive
+type KeyArchive struct {
+	Version       int               `json:"version"`
+	Created       time.Time         `json:"created"`
+	SourceKeyType string            `json:"source_key_type"`
+	Keys          []KeyArchiveEntry `json:"keys"`
+}
+
+type KeyArchiveEntry struct {
+	Name       string         `json:"name"`
+	Address    common.Address `json:"address"`
+	PrivateKey SecretHex      `json:"private_key"`
+}
+
+func ArchiveCmd() *cli.Command {
+	var archiveCmd = &cli.Command{
+		Name:  "archive",
+		Usage: "export or import every key of local keystore as one encrypted archive",
+		Subcommands: []*cli.Command{
+			ArchiveExportCmd(),
+			ArchiveImportCmd(),
+		},
+	}
+	return archiveCmd
+}
+
+func ArchiveExportCmd() *cli.Command {
+	var archiveExportCmd = &cli.Command{
+		Name:      "export",
+		Usage:     "write every key of local keystore into an encrypted archive",
+		UsageText: "export --out <file> [--new-password-file <file>]",
+		Flags: []cli.Flag{
+			&KeyStoreType,
+			&InsecureFlag,
+			&ArchiveOutFlag,
+			&NewPasswordFileFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return ExportArchiveCmd(cCtx)
+		},
+	}
+	return archiveExportCmd
+}
+
+func ArchiveImportCmd() *cli.Command {
+	var archiveImportCmd = &cli.Command{
+		Name:      "import",
+		Usage:     "restore the keys of an encrypted archive into local keystore",
+		UsageText: "import --in <file>",
+		Flags: []cli.Flag{
+			&KeyStoreType,
+			&InsecureFlag,
+			&ArchiveInFlag,
+			&OnConflictFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return ImportArchiveCmd(cCtx)
+		},
+	}
+	return archiveImportCmd
+}
+
+func ExportArchiveCmd(cCtx *cli.Context) error {
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+	outFile := cCtx.String("out")
+
+	if !AllowKeyOverwrite(outFile) {
+		return nil
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	keyNames, err := keyStore.KeyNames()
+	if err != nil {
+		return err
+	}
+
+	archive := KeyArchive{Version: KeyArchiveVersion, Created: time.Now().UTC(), SourceKeyType: keyType}
+	defer archive.wipe()
+	for _, keyName := range keyNames {
+		privateKey, err := keyStore.Load(keyName)
+		if err != nil {
+			return err
+		}
+		archive.Keys = append(archive.Keys, KeyArchiveEntry{
+			Name:       keyName,
+			Address:    GetPublicAddressFromPrivateKey(privateKey),
+			PrivateKey: crypto.FromECDSA(privateKey),
+		})
+		WipePrivateKey(privateKey)
+	}
+
+	plainText, err := json.Marshal(archive)
+	if err != nil {
+		return fmt.Errorf("Error encoding archive: %w", err)
+	}
+	defer WipeBytes(plainText)
+
+	// a mistyped archive password can't be recovered from, so it is asked
+	// twice like the password of a new keystore
+	password, err := GetNewPasswordFromPrompt(cCtx.Context, insecure, "encrypt the archive")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(password)
+
+	kdf, err := NewEnvelopeKDF()
+	if err != nil {
+		return err
+	}
+
+	envelope, err := SealEnvelope(KeyArchiveVersion, kdf, password, plainText)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding archive: %w", err)
+	}
+
+	if err := WriteFileAtomic(outFile, data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d keys to: %s\n", len(archive.Keys), outFile)
+	return nil
+}
+
+func ImportArchiveCmd(cCtx *cli.Context) error {
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+	inFile := cCtx.String("in")
+	onConflict := cCtx.String("on-conflict")
+
+	switch onConflict {
+	case ConflictPrompt, ConflictSkip, ConflictOverwrite, ConflictRename:
+	default:
+		return fmt.Errorf("%s: %w", onConflict, ErrInvalidConflictMode)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	archive, err := ReadKeyArchive(cCtx.Context, inFile)
+	if err != nil {
+		return err
+	}
+	defer archive.wipe()
+	fmt.Printf("Archive of %d %s keys created on %s\n", len(archive.Keys), archive.SourceKeyType, archive.Created.Format("02-01-2006 15:04:05"))
+
+	keyNames, err := keyStore.KeyNames()
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, keyName := range keyNames {
+		existing[keyName] = true
+	}
+
+	for _, entry := range archive.Keys {
+		if err := importArchiveEntry(keyStore, keyType, entry, existing, onConflict, insecure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importArchiveEntry(keyStore KeyStore, keyType string, entry KeyArchiveEntry, existing map[string]bool, onConflict string, insecure bool) error {
+	privateKey, err := crypto.ToECDSA(entry.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("Error decoding key %s: %w", entry.Name, err)
+	}
+	defer WipePrivateKey(privateKey)
+
+	if GetPublicAddressFromPrivateKey(privateKey) != entry.Address {
+		return fmt.Errorf("Error importing key %s: %w", entry.Name, ErrAddressMismatch)
+	}
+
+	keyName := entry.Name
+	if existing[keyName] {
+		switch onConflict {
+		case ConflictSkip:
+			fmt.Printf("Skipped existing key: %s\n", keyName)
+			return nil
+		case ConflictRename:
+			keyName = uniqueKeyName(keyName, existing)
+		case ConflictPrompt:
+			fmt.Printf("%s: ", keyName)
+			if !ConfirmKeyOverwrite() {
+				return nil
+			}
+		}
+	}
+
+	if err := keyStore.Save(keyName, privateKey, insecure); err != nil {
+		return err
+	}
+	if err := RecordKeyMetadata(keyType, keyName, entry.Address, KeySourceArchive); err != nil {
+		return err
+	}
+	existing[keyName] = true
+	fmt.Printf("Imported key: %s %s\n", keyName, entry.Address.Hex())
+	return nil
+}
+
+func ReadKeyArchive(ctx context.Context, inFile string) (KeyArchive, error) {
+	data, err := os.ReadFile(inFile)
+	if err != nil {
+		return KeyArchive{}, fmt.Errorf("Error reading archive: %w", err)
+	}
+
+	var envelope EncryptedEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return KeyArchive{}, fmt.Errorf("Error parsing archive: %w", err)
+	}
+
+	if envelope.Version > KeyArchiveVersion {
+		return KeyArchive{}, fmt.Errorf("archive version %d: %w", envelope.Version, ErrUnsupportedEnvelope)
+	}
+
+	password, err := GetPasswordFromPrompt(ctx, true, "decrypt the archive")
+	if err != nil {
+		return KeyArchive{}, err
+	}
+	defer WipeBytes(password)
+
+	plainText, err := OpenEnvelope(envelope, password)
+	if err != nil {
+		return KeyArchive{}, fmt.Errorf("Error decrypting archive: %w", err)
+	}
+	defer WipeBytes(plainText)
+
+	var archive KeyArchive
+	if err := json.Unmarshal(plainText, &archive); err != nil {
+		return KeyArchive{}, fmt.Errorf("Error parsing decrypted archive: %w", err)
+	}
+
+	return archive, nil
+}
+
+// wipe overwrites the private keys of the archive once they are stored
+func (archive *KeyArchive) wipe() {
+	for _, entry := range archive.Keys {
+		WipeBytes(entry.PrivateKey)
+	}
+}
+
+func uniqueKeyName(keyName string, existing map[string]bool) string {
+	for i := 1; ; i++ {
+		candidate := keyName + "-" + strconv.Itoa(i)
+		if !existing[candidate] {
+			return candidate
+		}
+	}
+}
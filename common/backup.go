@@ -0,0 +1,299 @@
+package wc_common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+// KeyShare is one Shamir share of a private key as written to disk. The
+// checksum covers every other field, so a corrupted or edited share is
+// detected before it is combined
+type KeyShare struct {
+	Version   int            `json:"version"`
+	Index     byte           `json:"index"`
+	Threshold int            `json:"threshold"`
+	Shares    int            `json:"shares"`
+	Address   common.Address `json:"address"`
+	Share     string         `json:"share"`
+	Checksum  string         `json:"checksum"`
+}
+
+func BackupCmd() *cli.Command {
+	var backupCmd = &cli.Command{
+		Name:      "backup",
+		Usage:     "split a key from local keystore into Shamir shares",
+		UsageText: "backup --key-name <keyName> --shares <N> --threshold <M> --out-dir <dir>",
+		Flags: []cli.Flag{
+			&KeyNameFlag,
+			&KeyStoreType,
+			&SharesFlag,
+			&ThresholdFlag,
+			&OutputDirFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.String("key-name") == "" {
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
+			}
+			return BackupKeyCmd(cCtx)
+		},
+	}
+	return backupCmd
+}
+
+func RestoreCmd() *cli.Command {
+	var restoreCmd = &cli.Command{
+		Name:      "restore",
+		Usage:     "rebuild a key from Shamir shares and import it into local keystore",
+		UsageText: "restore --key-name <keyName> --share-file <file> --share-file <file> ...",
+		Flags: []cli.Flag{
+			&KeyNameFlag,
+			&KeyStoreType,
+			&InsecureFlag,
+			&ShareFileFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return RestoreKeyCmd(cCtx)
+		},
+	}
+	return restoreCmd
+}
+
+func BackupKeyCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+	shares := cCtx.Int("shares")
+	threshold := cCtx.Int("threshold")
+	outDir := cCtx.String("out-dir")
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := keyStore.Load(keyName)
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(privateKey)
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	keyBytes := crypto.FromECDSA(privateKey)
+	defer WipeBytes(keyBytes)
+
+	secrets, err := SplitSecret(keyBytes, shares, threshold)
+	if err != nil {
+		return fmt.Errorf("Error splitting key: %w", err)
+	}
+
+	if err := EnsureDirectory(outDir); err != nil {
+		return err
+	}
+
+	_, baseName := filepath.Split(keyName)
+	for i, secret := range secrets {
+		share := KeyShare{
+			Version:   KeyShareVersion,
+			Index:     byte(i + 1),
+			Threshold: threshold,
+			Shares:    shares,
+			Address:   address,
+			Share:     hex.EncodeToString(secret),
+		}
+		share.Checksum = share.computeChecksum()
+
+		data, err := json.MarshalIndent(share, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Error encoding share: %w", err)
+		}
+
+		shareFile := filepath.Join(outDir, fmt.Sprintf("%s.share-%d-of-%d.json", baseName, share.Index, shares))
+		if err := WriteFileAtomic(shareFile, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Written share: %s\n", shareFile)
+	}
+
+	fmt.Printf("Backed up key %s (%s) into %d shares, %d are needed to restore it\n", keyName, address.Hex(), shares, threshold)
+	return nil
+}
+
+func RestoreKeyCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+	shareFiles := cCtx.StringSlice("share-file")
+
+	var shares []KeyShare
+	for _, shareFile := range shareFiles {
+		share, err := ReadKeyShare(shareFile)
+		if err != nil {
+			fmt.Printf("Skipping share %s: %v\n", shareFile, err)
+			continue
+		}
+		shares = append(shares, share)
+	}
+
+	privateKeyBytes, err := CombineKeyShares(shares)
+	if err != nil {
+		return fmt.Errorf("Error restoring key: %w", err)
+	}
+	defer WipeBytes(privateKeyBytes)
+
+	privateKey, err := crypto.ToECDSA(privateKeyBytes)
+	if err != nil {
+		return fmt.Errorf("Error converting bytes to ECDSA private key: %w", err)
+	}
+	defer WipePrivateKey(privateKey)
+
+	address := GetPublicAddressFromPrivateKey(privateKey)
+	fmt.Printf("Restored key for address: %s\n", address.Hex())
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	if allow, err := AllowKeyStoreOverwrite(keyStore, keyName); err != nil || !allow {
+		return err
+	}
+
+	if err := keyStore.Save(keyName, privateKey, insecure); err != nil {
+		return err
+	}
+	if err := RecordKeyMetadata(keyType, keyName, address, KeySourceRestored); err != nil {
+		return err
+	}
+	fmt.Printf("Imported key: %s\n", keyName)
+	return nil
+}
+
+func ReadKeyShare(shareFile string) (KeyShare, error) {
+	var share KeyShare
+
+	data, err := os.ReadFile(shareFile)
+	if err != nil {
+		return share, err
+	}
+
+	if err := json.Unmarshal(data, &share); err != nil {
+		return share, err
+	}
+
+	if share.Version != KeyShareVersion || share.Checksum != share.computeChecksum() {
+		return share, ErrInvalidShare
+	}
+
+	return share, nil
+}
+
+// CombineKeyShares rebuilds a private key from shares of the same key. When
+// more shares than the threshold are given, every subset is tried until one
+// rebuilds the expected address
+func CombineKeyShares(shares []KeyShare) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	expected := shares[0]
+	for i, share := range shares {
+		if share.Address != expected.Address || share.Threshold != expected.Threshold {
+			return nil, ErrShareMismatch
+		}
+		for _, other := range shares[:i] {
+			if share.Index == other.Index {
+				return nil, fmt.Errorf("%w: share %d is given twice", ErrDuplicateShare, share.Index)
+			}
+		}
+	}
+
+	if len(shares) < expected.Threshold {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrNotEnoughShares, len(shares), expected.Threshold)
+	}
+
+	var privateKey []byte
+	forEachCombination(len(shares), expected.Threshold, func(subset []int) bool {
+		xs := make([]byte, len(subset))
+		ys := make([][]byte, len(subset))
+		for i, s := range subset {
+			xs[i] = shares[s].Index
+			y, err := hex.DecodeString(shares[s].Share)
+			if err != nil {
+				return false
+			}
+			ys[i] = y
+		}
+
+		secret, err := CombineShares(xs, ys)
+		if err != nil {
+			return false
+		}
+
+		key, err := crypto.ToECDSA(secret)
+		if err != nil || crypto.PubkeyToAddress(key.PublicKey) != expected.Address {
+			return false
+		}
+
+		privateKey = secret
+		return true
+	})
+
+	if privateKey == nil {
+		return nil, ErrShareMismatch
+	}
+	return privateKey, nil
+}
+
+func (share *KeyShare) computeChecksum() string {
+	payload := strings.Join([]string{
+		fmt.Sprint(share.Version),
+		fmt.Sprint(share.Index),
+		fmt.Sprint(share.Threshold),
+		fmt.Sprint(share.Shares),
+		share.Address.Hex(),
+		share.Share,
+	}, ":")
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// forEachCombination calls fn with every k sized subset of 0..n-1 until fn
+// returns true
+func forEachCombination(n int, k int, fn func([]int) bool) bool {
+	subset := make([]int, 0, k)
+	var walk func(start int) bool
+	walk = func(start int) bool {
+		if len(subset) == k {
+			return fn(subset)
+		}
+		for i := start; i <= n-(k-len(subset)); i++ {
+			subset = append(subset, i)
+			if walk(i + 1) {
+				return true
+			}
+			subset = subset[:len(subset)-1]
+		}
+		return false
+	}
+	return walk(0)
+}
@@ -0,0 +1,162 @@
+package wc_common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CreateKeysCmd creates count keys named prefix<index> in one session, the
+// password is asked only once as every keystore keeps it for the command
+func CreateKeysCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+	count := cCtx.Uint("count")
+	start := cCtx.Uint("start")
+	prefix := cCtx.String("prefix")
+	configFile := cCtx.String("write-config")
+
+	role := cCtx.String("role")
+	if role == "" {
+		role = KeyRoleWatchtower
+	}
+
+	// every key would get the same name, keys named by address are unique
+	if count > 1 && keyName != "" && prefix == "" {
+		return ErrKeyNameWithCount
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	var keyNames []string
+	for index := start; index < start+count; index++ {
+		name, err := createKey(cCtx, keyStore, keyType, keyName, prefix, index, role, insecure)
+		if err != nil {
+			return err
+		}
+		if name != "" {
+			keyNames = append(keyNames, name)
+		}
+	}
+
+	if configFile == "" || len(keyNames) == 0 {
+		return nil
+	}
+
+	keyPaths := make([]string, len(keyNames))
+	for i, name := range keyNames {
+		keyPaths[i] = keyStore.KeyPath(name)
+	}
+	return WriteConfigKeys(configFile, keyType, keyPaths)
+}
+
+// createKey creates the key at index of a bulk creation, the name is empty
+// when the user kept an existing key
+func createKey(cCtx *cli.Context, keyStore KeyStore, keyType string, keyName string, prefix string, index uint, role string, insecure bool) (string, error) {
+	privateKey, err := GenerateRandomKey()
+	if err != nil {
+		return "", err
+	}
+	defer WipePrivateKey(privateKey)
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	name := keyName
+	if prefix != "" {
+		name = prefix + strconv.FormatUint(uint64(index), 10)
+	} else if name == "" {
+		name = address.String()
+	}
+
+	if err := ValidateKeyName(name); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if allow, err := AllowKeyStoreOverwrite(keyStore, name); err != nil || !allow {
+		return "", err
+	}
+
+	if err := keyStore.Save(name, privateKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(keyType, name, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+	if _, err := UpdateKeyMetadata(keyType, name, role, cCtx.StringSlice("label"), nil); err != nil {
+		return "", err
+	}
+	fmt.Printf("Created key: %s %s\n", name, address.Hex())
+	return name, nil
+}
+
+// WriteConfigKeys adds keyPaths to watchtower_encrypted_keys of the config
+// file and sets its encrypted_key_type, the file is created when missing.
+// Every other field of an existing config is kept as is
+func WriteConfigKeys(configFile string, keyType string, keyPaths []string) error {
+	fields := map[string]json.RawMessage{}
+
+	data, err := os.ReadFile(configFile)
+	if err == nil {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return fmt.Errorf("Error parsing config file %s: %w", configFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("Error reading config file %s: %w", configFile, err)
+	}
+
+	var existingKeys []string
+	if raw, ok := fields["watchtower_encrypted_keys"]; ok {
+		if err := json.Unmarshal(raw, &existingKeys); err != nil {
+			return fmt.Errorf("Error parsing watchtower_encrypted_keys of %s: %w", configFile, err)
+		}
+	}
+
+	existingType := KeyTypeW3SecretKey
+	if raw, ok := fields["encrypted_key_type"]; ok {
+		if err := json.Unmarshal(raw, &existingType); err != nil {
+			return fmt.Errorf("Error parsing encrypted_key_type of %s: %w", configFile, err)
+		}
+	}
+	if len(existingKeys) != 0 && existingType != keyType {
+		return fmt.Errorf("config uses %s keys, not %s: %w", existingType, keyType, ErrKeyTypeMismatch)
+	}
+
+	present := map[string]bool{}
+	for _, keyPath := range existingKeys {
+		present[keyPath] = true
+	}
+	for _, keyPath := range keyPaths {
+		if !present[keyPath] {
+			existingKeys = append(existingKeys, keyPath)
+			present[keyPath] = true
+		}
+	}
+
+	fields["watchtower_encrypted_keys"], err = json.Marshal(existingKeys)
+	if err != nil {
+		return fmt.Errorf("Error encoding watchtower_encrypted_keys: %w", err)
+	}
+
+	fields["encrypted_key_type"], err = json.Marshal(keyType)
+	if err != nil {
+		return fmt.Errorf("Error encoding encrypted_key_type: %w", err)
+	}
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding config file: %w", err)
+	}
+
+	if err := WriteFileAtomic(configFile, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Written %d watchtower keys to config file: %s\n", len(existingKeys), configFile)
+	return nil
+}
@@ -21,6 +21,78 @@ const (
 	W3SecretKeyDirName    string = "." + KeyTypeW3SecretKey
 	W3SecretKeySuffixName string = ".ecdsa.key.json"
 
+	SeedDirName        string = ".seeds"
+	SeedSuffixName     string = ".seed.json"
+	SeedFileVersion    int    = 1
+	DefaultSeedName    string = "seed"
+	DefaultHDPath      string = "m/44'/60'/0'/0/i"
+	HDHardenedKeyStart uint32 = 0x80000000
+
+	KeyShareVersion int = 1
+
+	KeyArchiveVersion int    = 1
+	ConflictPrompt    string = "prompt"
+	ConflictSkip      string = "skip"
+	ConflictOverwrite string = "overwrite"
+	ConflictRename    string = "rename"
+
+	KeyMetadataFileName string = "keys.metadata.json"
+	KeyMetadataVersion  int    = 1
+	KeyRoleOperator     string = "operator"
+	KeyRoleWatchtower   string = "watchtower"
+	KeySourceCreated    string = "created"
+	KeySourceImported   string = "imported"
+	KeySourceDerived    string = "derived"
+	KeySourceRestored   string = "restored"
+	KeySourceArchive    string = "archive"
+	KeySourceMigrated   string = "migrated"
+	KeySourceRotated    string = "rotated"
+	KeySourceUnknown    string = "unknown"
+
+	RotatingKeySuffix string = "-rotating"
+	ArchivedKeySuffix string = "-archived-"
+
+	KeyTypeKeyVault  string = "keyvault"
+	KeyVaultDirName  string = "." + KeyTypeKeyVault
+	KeyVaultFileName string = "keyvault.json"
+	KeyVaultVersion  int    = 1
+
+	KeyTypePKCS11    string = "pkcs11"
+	PKCS11DirName    string = "." + KeyTypePKCS11
+	PKCS11ConfigName string = "pkcs11.json"
+
+	KeyTypeVaultTransit    string = "vault-transit"
+	VaultTransitDirName    string = "." + KeyTypeVaultTransit
+	VaultTransitConfigName string = "transit.json"
+	DefaultTransitMount    string = "transit"
+	DefaultTransitKeyType  string = "ecdsa-secp256k1"
+	DefaultAppRoleMount    string = "approle"
+	VaultAuthToken         string = "token"
+	VaultAuthAppRole       string = "approle"
+
+	NetworksFileName      string = "networks.json"
+	NetworksVersion       int    = 1
+	NetworkSourceBuiltIn  string = "built-in"
+	NetworkSourceUser     string = "user"
+	NetworkSourceOverride string = "override"
+
+	SecretRefEnv      string = "env:"
+	SecretRefFile     string = "file:"
+	SecretRefKeyStore string = "keystore:"
+
+	SignerSocketName   string = "signer.sock"
+	SignerAuditLogName string = "signer-audit.log"
+
+	EnvelopeCipher  string = "aes-256-gcm"
+	EnvelopeScryptN int    = 1 << 18
+	EnvelopeScryptR int    = 8
+	EnvelopeScryptP int    = 1
+	// bounds of the scrypt parameters read from a file, N = 2^20 with
+	// r = 8 takes 1 GiB
+	EnvelopeScryptMaxN int = 1 << 20
+	EnvelopeScryptMaxR int = 8
+	EnvelopeScryptMaxP int = 16
+
 	MinEntropyBits          float64 = 50
 	MaxMountRetries         int     = 5
 	RetryPeriodInSeconds    uint    = 1
@@ -29,51 +101,16 @@ const (
 	DefaultTxReceiptTimeout uint64  = 300
 )
 
+// ChainConfig is a network witnesschain is deployed on, as read from the
+// networks file. A GasPrice of -1 sends transactions with a zero gas price
 type ChainConfig struct {
-	OperatorRegistryAddress      common.Address
-	WitnessHubAddress            common.Address
-	AVSDirectoryAddress          common.Address
-	DiligenceProofManagerAddress common.Address
-	ChainID                      big.Int
-	BlockExplorer                string
-	GasPrice                     int
-}
-
-var BlueOrangutan = ChainConfig{
-	OperatorRegistryAddress:      common.HexToAddress("0x26710e60A36Ace8A44e1C3D7B33dc8B80eAb6cb7"),
-	DiligenceProofManagerAddress: common.HexToAddress("0x7AB3b14F3177935d4539d80289906633615393F2"),
-	ChainID:                      *big.NewInt(1237146866),
-	BlockExplorer:                "https://blue-orangutan-blockscout.eu-north-2.gateway.fm",
-	GasPrice:                     -1,
-}
-
-var Holesky = ChainConfig{
-	OperatorRegistryAddress: common.HexToAddress("0x708CBDDdab358c1fa8efB82c75bB4a116F316Def"),
-	WitnessHubAddress:       common.HexToAddress("0xa987EC494b13b21A8a124F8Ac03c9F530648C87D"),
-	AVSDirectoryAddress:     common.HexToAddress("0x055733000064333CaDDbC92763c58BF0192fFeBf"),
-	ChainID:                 *big.NewInt(17000),
-	BlockExplorer:           "https://holesky.etherscan.io",
-}
-
-var WitnesschainMainnet = ChainConfig{
-	OperatorRegistryAddress:      common.HexToAddress("0xd11e55b821aC8509D2C17f5f76193351252d69aE"),
-	DiligenceProofManagerAddress: common.HexToAddress("0x7AB3b14F3177935d4539d80289906633615393F2"),
-	BlockExplorer:                "https://explorer.witnesschain.com",
-	ChainID:                      *big.NewInt(1702448187),
-	GasPrice:                     -1,
-}
-
-var EthMainnet = ChainConfig{
-	OperatorRegistryAddress: common.HexToAddress("0xef1a89841fd189ba28e780a977ca70eb1a5e985d"),
-	WitnessHubAddress:       common.HexToAddress("0xD25c2c5802198CB8541987b73A8db4c9BCaE5cC7"),
-	AVSDirectoryAddress:     common.HexToAddress("0x135dda560e946695d6f155dacafc6f1f25c1f5af"),
-	ChainID:                 *big.NewInt(1),
-	BlockExplorer:           "https://etherscan.io",
-}
-
-var NetworkConfig = map[string]ChainConfig{
-	"1237146866": BlueOrangutan,
-	"17000":      Holesky,
-	"1702448187": WitnesschainMainnet,
-	"1":          EthMainnet,
+	Name                         string         `json:"name"`
+	RPCUrl                       string         `json:"rpc_url,omitempty"`
+	OperatorRegistryAddress      common.Address `json:"operator_registry"`
+	WitnessHubAddress            common.Address `json:"witness_hub"`
+	AVSDirectoryAddress          common.Address `json:"avs_directory"`
+	DiligenceProofManagerAddress common.Address `json:"diligence_proof_manager"`
+	ChainID                      big.Int        `json:"chain_id"`
+	BlockExplorer                string         `json:"block_explorer"`
+	GasPrice                     int            `json:"gas_price,omitempty"`
 }
@@ -0,0 +1,158 @@
+package wc_common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+type EnvelopeKDF struct {
+	Name string `json:"name"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// EncryptedEnvelope is a password encrypted blob as stored on disk by the
+// key vault and the keystore archives. The key is derived from the
+// password with scrypt and the data is sealed with AES-256-GCM, with the
+// other fields as additional data
+type EncryptedEnvelope struct {
+	Version    int         `json:"version"`
+	KDF        EnvelopeKDF `json:"kdf"`
+	Cipher     string      `json:"cipher"`
+	Nonce      string      `json:"nonce"`
+	CipherText string      `json:"ciphertext"`
+}
+
+// NewEnvelopeKDF returns scrypt parameters with a fresh random salt
+func NewEnvelopeKDF() (EnvelopeKDF, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return EnvelopeKDF{}, fmt.Errorf("Generating salt failed: %w", err)
+	}
+
+	return EnvelopeKDF{Name: "scrypt", N: EnvelopeScryptN, R: EnvelopeScryptR, P: EnvelopeScryptP, Salt: hex.EncodeToString(salt)}, nil
+}
+
+// SealEnvelope encrypts plainText with a fresh nonce
+func SealEnvelope(version int, kdf EnvelopeKDF, password []byte, plainText []byte) (EncryptedEnvelope, error) {
+	gcm, err := newEnvelopeCipher(password, kdf)
+	if err != nil {
+		return EncryptedEnvelope{}, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return EncryptedEnvelope{}, fmt.Errorf("Generating nonce failed: %w", err)
+	}
+
+	envelope := EncryptedEnvelope{
+		Version: version,
+		KDF:     kdf,
+		Cipher:  EnvelopeCipher,
+		Nonce:   hex.EncodeToString(nonce),
+	}
+	header, err := envelopeHeader(envelope)
+	if err != nil {
+		return EncryptedEnvelope{}, err
+	}
+	envelope.CipherText = hex.EncodeToString(gcm.Seal(nil, nonce, plainText, header))
+	return envelope, nil
+}
+
+// OpenEnvelope decrypts the envelope, a wrong password returns
+// ErrInvalidPassword and a malformed envelope ErrInvalidEnvelope
+func OpenEnvelope(envelope EncryptedEnvelope, password []byte) ([]byte, error) {
+	if envelope.KDF.Name != "scrypt" || envelope.Cipher != EnvelopeCipher {
+		return nil, ErrUnsupportedEnvelope
+	}
+	if err := checkEnvelopeKDF(envelope.KDF); err != nil {
+		return nil, err
+	}
+
+	nonce, err := hex.DecodeString(envelope.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding nonce: %w", ErrInvalidEnvelope)
+	}
+
+	cipherText, err := hex.DecodeString(envelope.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding ciphertext: %w", ErrInvalidEnvelope)
+	}
+
+	gcm, err := newEnvelopeCipher(password, envelope.KDF)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("nonce has %d bytes instead of %d: %w", len(nonce), gcm.NonceSize(), ErrInvalidEnvelope)
+	}
+
+	header, err := envelopeHeader(envelope)
+	if err != nil {
+		return nil, err
+	}
+	plainText, err := gcm.Open(nil, nonce, cipherText, header)
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	return plainText, nil
+}
+
+// checkEnvelopeKDF checks the scrypt parameters of a file, so that a
+// corrupted or crafted file can't make the derivation take all the memory
+func checkEnvelopeKDF(kdf EnvelopeKDF) error {
+	switch {
+	case kdf.N < 2 || kdf.N > EnvelopeScryptMaxN || kdf.N&(kdf.N-1) != 0:
+		return fmt.Errorf("scrypt N %d is not a power of 2 up to %d: %w", kdf.N, EnvelopeScryptMaxN, ErrInvalidEnvelope)
+	case kdf.R < 1 || kdf.R > EnvelopeScryptMaxR:
+		return fmt.Errorf("scrypt r %d is not between 1 and %d: %w", kdf.R, EnvelopeScryptMaxR, ErrInvalidEnvelope)
+	case kdf.P < 1 || kdf.P > EnvelopeScryptMaxP:
+		return fmt.Errorf("scrypt p %d is not between 1 and %d: %w", kdf.P, EnvelopeScryptMaxP, ErrInvalidEnvelope)
+	}
+	return nil
+}
+
+// envelopeHeader is the additional data of the cipher, the envelope
+// without its ciphertext, so that a changed field fails to open
+func envelopeHeader(envelope EncryptedEnvelope) ([]byte, error) {
+	envelope.CipherText = ""
+	header, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding envelope header: %w", err)
+	}
+	return header, nil
+}
+
+func newEnvelopeCipher(password []byte, kdf EnvelopeKDF) (cipher.AEAD, error) {
+	salt, err := hex.DecodeString(kdf.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding salt: %w", ErrInvalidEnvelope)
+	}
+
+	key, err := scrypt.Key(password, salt, kdf.N, kdf.R, kdf.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Error deriving encryption key: %w", err)
+	}
+	defer WipeBytes(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating cipher: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,253 @@
+package wc_common
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+// testEnvelopeKDF returns cheap scrypt parameters, the real ones take
+// about a second per derivation
+func testEnvelopeKDF(t *testing.T) EnvelopeKDF {
+	t.Helper()
+	kdf, err := NewEnvelopeKDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kdf.N = 1 << 10
+	return kdf
+}
+
+// testPasswordContext returns a command context whose password source
+// answers every password prompt with password
+func testPasswordContext(password string) context.Context {
+	passwords := &Passwords{password: passwordSource{set: true, password: []byte(password)}}
+	return WithKeyStores(context.Background(), NewKeyStores(passwords))
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	plainText := []byte("operator and watchtower keys")
+
+	envelope, err := SealEnvelope(1, testEnvelopeKDF(t), []byte("correct horse"), plainText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := OpenEnvelope(envelope, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plainText) {
+		t.Errorf("OpenEnvelope = %q, want %q", opened, plainText)
+	}
+}
+
+func TestEnvelopeWrongPassword(t *testing.T) {
+	envelope, err := SealEnvelope(1, testEnvelopeKDF(t), []byte("correct horse"), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenEnvelope(envelope, []byte("correct horsf")); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("OpenEnvelope error = %v, want ErrInvalidPassword", err)
+	}
+}
+
+func TestEnvelopeTampered(t *testing.T) {
+	password := []byte("correct horse")
+	envelope, err := SealEnvelope(1, testEnvelopeKDF(t), password, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flip := func(hexValue string) string {
+		data, _ := hex.DecodeString(hexValue)
+		data[len(data)-1] ^= 0x01
+		return hex.EncodeToString(data)
+	}
+
+	// the header is authenticated with the ciphertext
+	tampered := []EncryptedEnvelope{envelope, envelope, envelope, envelope}
+	tampered[0].CipherText = flip(envelope.CipherText)
+	tampered[1].Nonce = flip(envelope.Nonce)
+	tampered[2].KDF.Salt = flip(envelope.KDF.Salt)
+	tampered[3].Version++
+	for i, envelope := range tampered {
+		if _, err := OpenEnvelope(envelope, password); err == nil {
+			t.Errorf("tampered envelope %d was opened", i)
+		}
+	}
+
+	unsupported := envelope
+	unsupported.Cipher = "aes-128-cbc"
+	if _, err := OpenEnvelope(unsupported, password); !errors.Is(err, ErrUnsupportedEnvelope) {
+		t.Errorf("OpenEnvelope error = %v, want ErrUnsupportedEnvelope", err)
+	}
+}
+
+func TestEnvelopeCorrupted(t *testing.T) {
+	password := []byte("correct horse")
+	envelope, err := SealEnvelope(1, testEnvelopeKDF(t), password, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := map[string]func(*EncryptedEnvelope){
+		"short nonce":      func(e *EncryptedEnvelope) { e.Nonce = e.Nonce[:8] },
+		"empty nonce":      func(e *EncryptedEnvelope) { e.Nonce = "" },
+		"long nonce":       func(e *EncryptedEnvelope) { e.Nonce += "00" },
+		"nonce not hex":    func(e *EncryptedEnvelope) { e.Nonce = "zz" },
+		"ciphertext":       func(e *EncryptedEnvelope) { e.CipherText = "zz" },
+		"salt not hex":     func(e *EncryptedEnvelope) { e.KDF.Salt = "zz" },
+		"N too large":      func(e *EncryptedEnvelope) { e.KDF.N = 1 << 40 },
+		"N not power of 2": func(e *EncryptedEnvelope) { e.KDF.N = 1000 },
+		"N zero":           func(e *EncryptedEnvelope) { e.KDF.N = 0 },
+		"r too large":      func(e *EncryptedEnvelope) { e.KDF.R = 1 << 20 },
+		"p zero":           func(e *EncryptedEnvelope) { e.KDF.P = 0 },
+		"p too large":      func(e *EncryptedEnvelope) { e.KDF.P = 1 << 20 },
+	}
+	for name, corrupt := range corrupted {
+		corruptedEnvelope := envelope
+		corrupt(&corruptedEnvelope)
+		if _, err := OpenEnvelope(corruptedEnvelope, password); !errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("%s: OpenEnvelope error = %v, want ErrInvalidEnvelope", name, err)
+		}
+	}
+}
+
+func TestKeyArchiveRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := KeyArchive{
+		Version:       KeyArchiveVersion,
+		Created:       time.Now().UTC(),
+		SourceKeyType: KeyTypeW3SecretKey,
+		Keys: []KeyArchiveEntry{
+			{Name: "operator", Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: crypto.FromECDSA(key)},
+		},
+	}
+
+	plainText, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := SealEnvelope(KeyArchiveVersion, testEnvelopeKDF(t), []byte("archive password"), plainText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(envelope)
+	archiveFile := filepath.Join(t.TempDir(), "keys.archive")
+	if err := os.WriteFile(archiveFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadKeyArchive(testPasswordContext("archive password"), archiveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Keys) != 1 || read.Keys[0].Name != "operator" || !bytes.Equal(read.Keys[0].PrivateKey, crypto.FromECDSA(key)) {
+		t.Errorf("ReadKeyArchive = %+v, want the archived key", read.Keys)
+	}
+
+	if _, err := ReadKeyArchive(testPasswordContext("wrong password"), archiveFile); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("ReadKeyArchive error = %v, want ErrInvalidPassword", err)
+	}
+
+	envelope.Version = KeyArchiveVersion + 1
+	data, _ = json.Marshal(envelope)
+	if err := os.WriteFile(archiveFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyArchive(testPasswordContext("archive password"), archiveFile); !errors.Is(err, ErrUnsupportedEnvelope) {
+		t.Errorf("ReadKeyArchive error = %v, want ErrUnsupportedEnvelope", err)
+	}
+}
+
+// importArchiveContext returns the context of keys archive import reading
+// inFile into a key vault, with password for every prompt
+func importArchiveContext(inFile string, password string) *cli.Context {
+	set := flag.NewFlagSet("import", flag.ContinueOnError)
+	set.String("key-type", KeyTypeKeyVault, "")
+	set.String("in", inFile, "")
+	set.String("on-conflict", ConflictSkip, "")
+	set.Bool("insecure", true, "")
+	cCtx := cli.NewContext(nil, set, nil)
+	cCtx.Context = testPasswordContext(password)
+	return cCtx
+}
+
+func TestImportCorruptedArchive(t *testing.T) {
+	password := []byte("archive password")
+	envelope, err := SealEnvelope(KeyArchiveVersion, testEnvelopeKDF(t), password, []byte(`{"version":1,"keys":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notArchive, err := SealEnvelope(KeyArchiveVersion, testEnvelopeKDF(t), password, []byte("not an archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(corrupt func(*EncryptedEnvelope)) []byte {
+		corrupted := envelope
+		corrupt(&corrupted)
+		data, _ := json.Marshal(corrupted)
+		return data
+	}
+	valid := encode(func(e *EncryptedEnvelope) {})
+	notArchiveData, _ := json.Marshal(notArchive)
+
+	corrupted := map[string][]byte{
+		"truncated":    valid[:len(valid)/2],
+		"empty":        {},
+		"short nonce":  encode(func(e *EncryptedEnvelope) { e.Nonce = e.Nonce[:8] }),
+		"no nonce":     encode(func(e *EncryptedEnvelope) { e.Nonce = "" }),
+		"huge scrypt":  encode(func(e *EncryptedEnvelope) { e.KDF.N = 1 << 40 }),
+		"ciphertext":   encode(func(e *EncryptedEnvelope) { e.CipherText = e.CipherText[:len(e.CipherText)-2] }),
+		"other header": encode(func(e *EncryptedEnvelope) { e.KDF.P = 2 }),
+		"not archive":  notArchiveData,
+	}
+	dir := t.TempDir()
+	for name, data := range corrupted {
+		archiveFile := filepath.Join(dir, name+".archive")
+		if err := os.WriteFile(archiveFile, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := ImportArchiveCmd(importArchiveContext(archiveFile, string(password))); err == nil {
+			t.Errorf("%s: corrupted archive was imported", name)
+		}
+	}
+
+	// the keys of a readable archive are checked before they are stored
+	useTestKeyMetadata(t)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	entries := map[string]KeyArchiveEntry{
+		"short key":     {Name: "short", Address: address, PrivateKey: crypto.FromECDSA(key)[:16]},
+		"no key":        {Name: "none", Address: address},
+		"other address": {Name: "other", Address: common.Address{1}, PrivateKey: crypto.FromECDSA(key)},
+	}
+	keyStore := newMemoryKeyStore()
+	for name, entry := range entries {
+		if err := importArchiveEntry(keyStore, KeyTypeKeyVault, entry, map[string]bool{}, ConflictSkip, true); err == nil {
+			t.Errorf("%s: corrupted key was imported", name)
+		}
+	}
+	if len(keyStore.keys) != 0 {
+		t.Errorf("%d corrupted keys were stored", len(keyStore.keys))
+	}
+}
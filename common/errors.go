@@ -2,36 +2,67 @@ package wc_common
 
 import (
 	"errors"
-	"fmt"
-	"log"
 )
 
 var (
 	ErrEmptyKeyName              = errors.New("key name cannot be empty")
 	ErrKeyContainsWhitespaces    = errors.New("key name cannot contain spaces")
 	ErrInvalidPassword           = errors.New("invalid password")
+	ErrEmptyPassword             = errors.New("password cannot be empty")
+	ErrPasswordMismatch          = errors.New("passwords do not match")
 	ErrNotADirectory             = errors.New("is not a directory")
 	ErrInvalidEncryptedDirectory = errors.New("invalid gocryptfs encrypted directory")
 	ErrInvalidKeyType            = errors.New("invalid key type")
+	ErrEmptyPrivateKey           = errors.New("private key cannot be empty")
+	ErrKeyNotFound               = errors.New("key not found")
+	ErrKeyVaultExists            = errors.New("key vault already initialized")
+	ErrKeyStoreNotInitialized    = errors.New("keystore not initialized")
+	ErrInvalidKeyVault           = errors.New("invalid key vault")
+	ErrUnsupportedEnvelope       = errors.New("unsupported encryption")
+	ErrInvalidEnvelope           = errors.New("corrupted encrypted data")
+	ErrInvalidMnemonic           = errors.New("invalid mnemonic")
+	ErrInvalidHDKey              = errors.New("invalid hd key, try the next index")
+	ErrInvalidHDPath             = errors.New("invalid derivation path, it must end with the /i index placeholder")
+	ErrInvalidHDIndex            = errors.New("hd key index is in the hardened range")
+	ErrInvalidShareCount         = errors.New("threshold must be at least 2 and at most the number of shares (max 255)")
+	ErrInvalidShare              = errors.New("invalid share, checksum mismatch")
+	ErrDuplicateShare            = errors.New("duplicate share")
+	ErrNotEnoughShares           = errors.New("not enough valid shares")
+	ErrShareMismatch             = errors.New("shares do not rebuild the expected address")
+	ErrAddressMismatch           = errors.New("key does not match the expected address")
+	ErrInvalidConflictMode       = errors.New("invalid conflict mode (prompt/skip/overwrite/rename)")
+	ErrSameKeyType               = errors.New("source and target key types are the same")
+	ErrKeyTypeMismatch           = errors.New("all the keys of a config must use the same key type")
+	ErrInvalidKeyRole            = errors.New("invalid key role (operator/watchtower)")
+	ErrRevealNotConfirmed        = errors.New("export prints the private key, confirm with --reveal-private-key or use keys show")
+	ErrKeySelection              = errors.New("set either --key-name or --all")
+	ErrKeyNameWithCount          = errors.New("--key-name names a single key, use --prefix to create several keys")
+	ErrGocryptfsNotInstalled     = errors.New("gocryptfs is not installed")
+	ErrAlreadyMounted            = errors.New("gocryptfs filesystem already mounted")
+	ErrWrongChain                = errors.New("witnesschain contracts are not deployed on this chain")
+	ErrNotWhitelisted            = errors.New("operator is not whitelisted")
+	ErrAlreadyRegistered         = errors.New("already registered")
+	ErrNotRegistered             = errors.New("not registered")
+	ErrTransactionFailed         = errors.New("transaction submitted successfully but failed to execute")
+	ErrZeroOperatorAddress       = errors.New("operator address is zero, set operator_address or an operator key")
+	ErrUnknownSigningKey         = errors.New("no key is served for this address")
+	ErrSigningDenied             = errors.New("signing denied by the allowlist of the key")
+	ErrKeyNotExtractable         = errors.New("the key never leaves the token, it can only sign")
+	ErrTokenNotFound             = errors.New("pkcs11 token not found")
+	ErrInvalidSignature          = errors.New("signature does not match the public key")
+	ErrKeyExists                 = errors.New("key already exists")
+	ErrNotSupported              = errors.New("not supported by this key type")
+	ErrUnsupportedCurve          = errors.New("key is not a secp256k1 key")
+	ErrInvalidVaultAuth          = errors.New("invalid vault auth method (token/approle)")
+	ErrVaultBadRequest           = errors.New("vault refused the request")
+	ErrInvalidConfig             = errors.New("invalid config")
+	ErrChainMismatch             = errors.New("rpc is on another chain than the chosen network")
+	ErrUnknownNetwork            = errors.New("unknown network, see networks list")
+	ErrNetworkExists             = errors.New("network name already used")
+	ErrBuiltInNetwork            = errors.New("built-in networks can't be removed, add a network with the same chain id to override it")
+	ErrInvalidNetwork            = errors.New("invalid network")
+	ErrUnknownProfile            = errors.New("profile not found in the config file")
+	ErrInvalidSecretRef          = errors.New("invalid secret reference (env:VAR/file:path/keystore:name)")
+	ErrRawKeyInConfig            = errors.New("raw private keys in the config file, use env:, file: or keystore: references")
+	ErrInsecureListenAddress     = errors.New("the signer only listens on a unix socket or on localhost with tls")
 )
-
-func CheckErrorWithoutUnmount(err error, description string) {
-	if err != nil {
-		FatalErrorWithoutUnmount(fmt.Sprintf("%s: %v\n", description, err))
-	}
-}
-
-func CheckError(err error, description string) {
-	if err != nil {
-		FatalError(fmt.Sprintf("%s: %v\n", description, err))
-	}
-}
-
-func FatalError(description string) {
-	Unmount()
-	log.Fatalf(description)
-}
-
-func FatalErrorWithoutUnmount(description string) {
-	log.Fatalf(description)
-}
@@ -1,6 +1,10 @@
 package wc_common
 
-import "github.com/urfave/cli/v2"
+import (
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
 
 var (
 	KeyNameFlag = cli.StringFlag{
@@ -12,7 +16,7 @@ var (
 	KeyStoreType = cli.StringFlag{
 		Name:    "key-type",
 		Aliases: []string{"t"},
-		Usage:   "Type of the key to be initialized (gocryptfs/w3secretkeys)",
+		Usage:   "Type of the key to be initialized (gocryptfs/w3secretkeys/keyvault/pkcs11/vault-transit)",
 		Value:   KeyTypeW3SecretKey,
 	}
 
@@ -23,10 +27,282 @@ var (
 		EnvVars: []string{"INSECURE"},
 	}
 
+	PasswordFileFlag = cli.StringFlag{
+		Name:    "password-file",
+		Usage:   "Read the password from the first line of this file instead of prompting",
+		EnvVars: []string{"PASSWORD_FILE"},
+	}
+
+	PasswordFdFlag = cli.IntFlag{
+		Name:  "password-fd",
+		Usage: "Read the password from the first line of this file descriptor instead of prompting",
+	}
+
+	NewPasswordFileFlag = cli.StringFlag{
+		Name:  "new-password-file",
+		Usage: "Read the new password from the first line of this file instead of prompting",
+	}
+
+	FromFileFlag = cli.StringFlag{
+		Name:  "from-file",
+		Usage: "Import the key from a V3 keystore JSON file or a hex key file, use - for stdin",
+	}
+
+	KeyPasswordFileFlag = cli.StringFlag{
+		Name:  "key-password-file",
+		Usage: "Read the password of the V3 keystore file from this file instead of prompting",
+	}
+
+	RoleFlag = cli.StringFlag{
+		Name:  "role",
+		Usage: "Role of the key (operator/watchtower)",
+	}
+
+	LabelFlag = cli.StringSliceFlag{
+		Name:  "label",
+		Usage: "Label to add to the key, repeat the flag for every label",
+	}
+
+	RemoveLabelFlag = cli.StringSliceFlag{
+		Name:  "remove-label",
+		Usage: "Label to remove from the key, repeat the flag for every label",
+	}
+
+	StatusFlag = cli.BoolFlag{
+		Name:  "status",
+		Usage: "Query the registration of every key on the chains of the config file",
+	}
+
+	JSONFlag = cli.BoolFlag{
+		Name:  "json",
+		Usage: "Print the output as JSON",
+	}
+
+	RevealPrivateKeyFlag = cli.BoolFlag{
+		Name:  "reveal-private-key",
+		Usage: "Confirm that the private key is printed to the terminal",
+	}
+
+	MnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Create a BIP-39 mnemonic seed instead of a single key",
+	}
+
+	SeedNameFlag = cli.StringFlag{
+		Name:  "seed",
+		Usage: "Name of the mnemonic seed to derive keys from",
+		Value: DefaultSeedName,
+	}
+
+	CountFlag = cli.UintFlag{
+		Name:  "count",
+		Usage: "Number of keys",
+		Value: 1,
+	}
+
+	StartIndexFlag = cli.UintFlag{
+		Name:  "start",
+		Usage: "First key index, appended to the prefix",
+	}
+
+	DerivationPathFlag = cli.StringFlag{
+		Name:  "path",
+		Usage: "BIP-32 derivation path ending with /i, the i is replaced by the key index",
+		Value: DefaultHDPath,
+	}
+
+	KeyPrefixFlag = cli.StringFlag{
+		Name:  "prefix",
+		Usage: "Prefix of the key names, the key index is appended to it",
+	}
+
+	WriteConfigFlag = cli.StringFlag{
+		Name:  "write-config",
+		Usage: "Add the created keys to watchtower_encrypted_keys of this config file, it is created when missing",
+	}
+
+	SharesFlag = cli.IntFlag{
+		Name:  "shares",
+		Usage: "Number of Shamir shares to split the key into",
+		Value: 5,
+	}
+
+	ThresholdFlag = cli.IntFlag{
+		Name:  "threshold",
+		Usage: "Number of Shamir shares needed to restore the key",
+		Value: 3,
+	}
+
+	OutputDirFlag = cli.StringFlag{
+		Name:  "out-dir",
+		Usage: "Directory where the files are written",
+		Value: ".",
+	}
+
+	ShareFileFlag = cli.StringSliceFlag{
+		Name:     "share-file",
+		Usage:    "Shamir share file, repeat the flag for every share",
+		Required: true,
+	}
+
+	ArchiveOutFlag = cli.StringFlag{
+		Name:     "out",
+		Usage:    "Path of the archive to write",
+		Required: true,
+	}
+
+	ArchiveInFlag = cli.StringFlag{
+		Name:     "in",
+		Usage:    "Path of the archive to read",
+		Required: true,
+	}
+
+	OnConflictFlag = cli.StringFlag{
+		Name:  "on-conflict",
+		Usage: "What to do when a key name already exists (prompt/skip/overwrite/rename)",
+		Value: ConflictPrompt,
+	}
+
+	FromKeyTypeFlag = cli.StringFlag{
+		Name:     "from",
+		Usage:    "Type of the keystore to move the keys from (gocryptfs/w3secretkeys/keyvault/pkcs11/vault-transit)",
+		Required: true,
+	}
+
+	ToKeyTypeFlag = cli.StringFlag{
+		Name:     "to",
+		Usage:    "Type of the keystore to move the keys to (gocryptfs/w3secretkeys/keyvault/pkcs11/vault-transit)",
+		Required: true,
+	}
+
+	AllKeysFlag = cli.BoolFlag{
+		Name:  "all",
+		Usage: "Use every key of the keystore",
+	}
+
+	DeleteSourceFlag = cli.BoolFlag{
+		Name:  "delete-source",
+		Usage: "Delete the keys from the source keystore once they are moved",
+	}
+
 	ConfigPathFlag = cli.StringFlag{
 		Name:    "config-file",
 		Aliases: []string{"c"},
 		Usage:   "Path of the config file",
 		EnvVars: []string{"CONFIG_PATH"},
 	}
+
+	ProfileFlag = cli.StringFlag{
+		Name:    "profile",
+		Usage:   "Name of the profile of the config file, its fields replace the fields of the base section",
+		EnvVars: []string{"CONFIG_PROFILE"},
+	}
+
+	StrictSecretsFlag = cli.BoolFlag{
+		Name:    "strict-secrets",
+		Usage:   "Refuse a config file with raw private keys instead of warning",
+		EnvVars: []string{"STRICT_SECRETS"},
+	}
+
+	KeyNamesFlag = cli.StringSliceFlag{
+		Name:    "key-name",
+		Aliases: []string{"k"},
+		Usage:   "Name of the key to serve, repeat the flag for every key",
+	}
+
+	AllowlistFlag = cli.StringFlag{
+		Name:  "allowlist",
+		Usage: "Path of the JSON file with what each key may sign",
+	}
+
+	UnixSocketFlag = cli.StringFlag{
+		Name:  "unix-socket",
+		Usage: "Path of the unix socket to listen on",
+		Value: filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, SignerSocketName),
+	}
+
+	ListenFlag = cli.StringFlag{
+		Name:  "listen",
+		Usage: "Localhost address to listen on with tls instead of the unix socket, e.g. 127.0.0.1:9000",
+	}
+
+	TLSCertFlag = cli.StringFlag{
+		Name:  "tls-cert",
+		Usage: "Path of the tls certificate used with --listen",
+	}
+
+	TLSKeyFlag = cli.StringFlag{
+		Name:  "tls-key",
+		Usage: "Path of the tls private key used with --listen",
+	}
+
+	AuditLogFlag = cli.StringFlag{
+		Name:  "audit-log",
+		Usage: "Path of the file every signing request is appended to",
+		Value: filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, SignerAuditLogName),
+	}
+
+	SignerRPCUrlsFlag = cli.StringSliceFlag{
+		Name:  "rpc-url",
+		Usage: "RPC url of a chain whose encoded EIP-712 messages are signed, repeat the flag for every chain",
+	}
+
+	NetworkFlag = cli.StringFlag{
+		Name:     "network",
+		Aliases:  []string{"n"},
+		Usage:    "Name or chain id of the network",
+		Required: true,
+	}
+
+	NetworkNameFlag = cli.StringFlag{
+		Name:     "name",
+		Usage:    "Name of the network",
+		Required: true,
+	}
+
+	ChainIDFlag = cli.Uint64Flag{
+		Name:     "chain-id",
+		Usage:    "Chain id of the network",
+		Required: true,
+	}
+
+	CopyNetworkFlag = cli.StringFlag{
+		Name:  "copy-from",
+		Usage: "Name or chain id of a network to copy the contract addresses from, e.g. for a fork",
+	}
+
+	RPCUrlFlag = cli.StringFlag{
+		Name:  "rpc-url",
+		Usage: "Default RPC url of the network",
+	}
+
+	OperatorRegistryFlag = cli.StringFlag{
+		Name:  "operator-registry",
+		Usage: "Address of the OperatorRegistry contract",
+	}
+
+	WitnessHubFlag = cli.StringFlag{
+		Name:  "witness-hub",
+		Usage: "Address of the WitnessHub contract, on chains where operators register to the AVS",
+	}
+
+	AVSDirectoryFlag = cli.StringFlag{
+		Name:  "avs-directory",
+		Usage: "Address of the EigenLayer AVSDirectory contract, on chains where operators register to the AVS",
+	}
+
+	DiligenceProofManagerFlag = cli.StringFlag{
+		Name:  "diligence-proof-manager",
+		Usage: "Address of the DiligenceProofManager contract, on proof submission chains",
+	}
+
+	BlockExplorerFlag = cli.StringFlag{
+		Name:  "block-explorer",
+		Usage: "Url of the block explorer, transactions are printed as links to it",
+	}
+
+	GasPriceFlag = cli.IntFlag{
+		Name:  "gas-price",
+		Usage: "Set -1 on chains where transactions have a zero gas price",
+	}
 )
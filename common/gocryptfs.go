@@ -0,0 +1,321 @@
+package wc_common
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var m_gocryptfsDirName string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, GoCryptFSDirName)
+var m_gocryptfsEncDir string = filepath.Join(m_gocryptfsDirName, GocryptfsEncDirName)
+var m_gocryptfsDecDir string = filepath.Join(m_gocryptfsDirName, GocryptfsDecDirName)
+var m_goCryptFSConfig string = filepath.Join(m_gocryptfsEncDir, GoCryptFSConfigName)
+
+// GocryptfsKeyStore keeps plain key files inside a gocryptfs volume that
+// is mounted on demand
+type GocryptfsKeyStore struct {
+	passwords *Passwords
+}
+
+func init() {
+	RegisterKeyStore(KeyTypeGoCryptFS, func(options KeyStoreOptions) KeyStore {
+		return &GocryptfsKeyStore{passwords: options.Passwords}
+	})
+}
+
+func (ks *GocryptfsKeyStore) Init(insecure bool) error {
+	if err := CheckIfGocryptfsIsInstalled(); err != nil {
+		return err
+	}
+
+	for _, dir := range []string{m_gocryptfsDirName, m_gocryptfsEncDir, m_gocryptfsDecDir} {
+		if err := EnsureDirectory(dir); err != nil {
+			return err
+		}
+	}
+	return InitGocryptfs(ks.passwords, insecure)
+}
+
+func (ks *GocryptfsKeyStore) Create(keyName string, insecure bool) (string, error) {
+	if err := ValidateAndMount(ks.passwords); err != nil {
+		return "", err
+	}
+
+	privateKey, err := GenerateRandomKey()
+	if err != nil {
+		return "", err
+	}
+	defer WipePrivateKey(privateKey)
+
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyFile := filepath.Join(m_gocryptfsDecDir, keyName)
+
+	if !AllowKeyOverwrite(keyFile) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privateKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeGoCryptFS, keyName, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key: %s\n", keyName)
+	return keyName, nil
+}
+
+func (ks *GocryptfsKeyStore) Import(keyName string, insecure bool) (string, error) {
+	if err := ValidateAndMount(ks.passwords); err != nil {
+		return "", err
+	}
+
+	hexKey, err := GetPrivateKeyFromUser()
+	if err != nil {
+		return "", err
+	}
+	defer WipeBytes(hexKey)
+
+	privKey, err := ParsePrivateKey(hexKey)
+	if err != nil {
+		return "", fmt.Errorf("Error converting hex string to ECDSA private key: %w", err)
+	}
+	defer WipePrivateKey(privKey)
+
+	address := GetPublicAddressFromPrivateKey(privKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyFile := filepath.Join(m_gocryptfsDecDir, keyName)
+
+	if !AllowKeyOverwrite(keyFile) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeGoCryptFS, keyName, address, KeySourceImported); err != nil {
+		return "", err
+	}
+	fmt.Printf("Imported key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+func (ks *GocryptfsKeyStore) Export(keyName string) error {
+	if err := ValidateAndMount(ks.passwords); err != nil {
+		return err
+	}
+
+	privateKey, err := GetGocryptfsPrivateKey(keyName)
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(privateKey)
+
+	fmt.Println("Public key : ", GetPublicAddressFromPrivateKey(privateKey))
+	fmt.Println("Private key : ", hex.EncodeToString(crypto.FromECDSA(privateKey)))
+	return nil
+}
+
+func (ks *GocryptfsKeyStore) Delete(keyName string) error {
+	if err := ValidateAndMount(ks.passwords); err != nil {
+		return err
+	}
+
+	if err := os.Remove(GetSanitizedGocryptfsKeyName(keyName)); err != nil {
+		return fmt.Errorf("Error deleting key: %w", err)
+	}
+	return nil
+}
+
+func (ks *GocryptfsKeyStore) List() error {
+	return ListKeyDir(KeyTypeGoCryptFS, m_gocryptfsEncDir, "", GoCryptFSConfigName)
+}
+
+func (ks *GocryptfsKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	if err := mountIfNeeded(ks.passwords); err != nil {
+		return nil, err
+	}
+
+	return GetGocryptfsPrivateKey(keyNameFromPath(keyPath))
+}
+
+func (ks *GocryptfsKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	if err := mountIfNeeded(ks.passwords); err != nil {
+		return err
+	}
+
+	hexKey := EncodePrivateKeyHex(privateKey)
+	defer WipeBytes(hexKey)
+
+	keyFile := filepath.Join(m_gocryptfsDecDir, keyName)
+	return CreateKeyFileAndStoreKey(keyFile, hexKey)
+}
+
+func (ks *GocryptfsKeyStore) KeyNames() ([]string, error) {
+	if err := mountIfNeeded(ks.passwords); err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(m_gocryptfsDecDir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading directory: %w", err)
+	}
+
+	var keyNames []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		keyNames = append(keyNames, file.Name())
+	}
+	return keyNames, nil
+}
+
+// ChangePassword changes the password of the gocryptfs volume, the keys
+// themselves are not encrypted one by one
+func (ks *GocryptfsKeyStore) ChangePassword(keyName string, insecure bool) error {
+	if err := CheckIfGocryptfsIsInstalled(); err != nil {
+		return err
+	}
+
+	if !ValidEncryptedDir() {
+		return fmt.Errorf("%w: check if %s exist. Or try initiating again after deleting those directories",
+			ErrInvalidEncryptedDirectory, m_goCryptFSConfig)
+	}
+
+	if keyName != "" {
+		fmt.Println("All keys of the gocryptfs volume share one password, changing it for the whole volume")
+	}
+
+	oldPassword, err := ks.passwords.Password(true, "unlock gocryptfs volume")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(oldPassword)
+
+	newPassword, err := ks.passwords.NewPassword(insecure, "encrypt gocryptfs volume")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(newPassword)
+
+	// gocryptfs reads the old and the new password from stdin, one per
+	// line, and replaces gocryptfs.conf atomically
+	stdin := bytes.Join([][]byte{oldPassword, newPassword, nil}, []byte("\n"))
+	defer WipeBytes(stdin)
+
+	passwdCmd := exec.Command("gocryptfs", "-passwd", m_gocryptfsEncDir)
+	passwdCmd.Stdin = bytes.NewReader(stdin)
+	output, err := passwdCmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Error changing gocryptfs password. Output - %s: %w", output, err)
+	}
+
+	fmt.Printf("Changed password of gocryptfs volume: %s\n", m_gocryptfsEncDir)
+	return nil
+}
+
+func (ks *GocryptfsKeyStore) UseKeyPath(keyPath string) {
+	dir, file := filepath.Split(keyPath)
+	if file != keyPath {
+		// go to the grand parent directory of the key path to get the .encrypted_keys path
+		parentPathGoCryptFS := filepath.Dir(filepath.Dir(dir))
+		m_gocryptfsEncDir = filepath.Join(parentPathGoCryptFS, GocryptfsEncDirName)
+		m_gocryptfsDecDir = filepath.Join(parentPathGoCryptFS, GocryptfsDecDirName)
+		m_goCryptFSConfig = filepath.Join(m_gocryptfsEncDir, GoCryptFSConfigName)
+		m_isFullPath = true
+	}
+	fmt.Printf("Using the key path : %s\n", m_gocryptfsEncDir)
+}
+
+func (ks *GocryptfsKeyStore) KeyPath(keyName string) string {
+	return filepath.Join(m_gocryptfsDecDir, keyName)
+}
+
+// Lock has nothing to wipe, the password is only held while mounting
+func (ks *GocryptfsKeyStore) Lock() {}
+
+func mountIfNeeded(passwords *Passwords) error {
+	if !m_isMounted {
+		return ValidateAndMount(passwords)
+	}
+	return nil
+}
+
+func InitGocryptfs(passwords *Passwords, insecure bool) error {
+	initCmd := exec.Command("gocryptfs", "-init", "-plaintextnames", m_gocryptfsEncDir)
+
+	password, err := passwords.Password(insecure, "init")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(password)
+
+	return RunCommandWithPassword(initCmd, "init", password)
+}
+
+func CreateKeyFileAndStoreKey(keyFile string, privateKey []byte) error {
+	file, err := os.Create(keyFile)
+	if err != nil {
+		return fmt.Errorf("Error creating file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(privateKey); err != nil {
+		return fmt.Errorf("Error writing to file: %w", err)
+	}
+	return nil
+}
+
+func ValidEncryptedDir() bool {
+	_, err := os.Stat(m_goCryptFSConfig)
+
+	return !os.IsNotExist(err)
+}
+
+func GetGocryptfsPrivateKey(keyName string) (*ecdsa.PrivateKey, error) {
+	keyFile := GetSanitizedGocryptfsKeyName(keyName)
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key file %s: %w", keyFile, err)
+	}
+	defer WipeBytes(data)
+
+	privateKey, err := ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing key file %s: %w", keyFile, err)
+	}
+	return privateKey, nil
+}
+
+func GetSanitizedGocryptfsKeyName(keyName string) string {
+	keyFile := keyName
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(m_gocryptfsDecDir, keyName)
+	}
+
+	return keyFile
+}
@@ -0,0 +1,320 @@
+package wc_common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+	"github.com/urfave/cli/v2"
+)
+
+var m_seedDir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, SeedDirName)
+
+// seedFile stores a BIP-39 mnemonic encrypted the same way as the web3
+// secret storage keys
+type seedFile struct {
+	Version int                 `json:"version"`
+	Crypto  keystore.CryptoJSON `json:"crypto"`
+}
+
+func DeriveCmd() *cli.Command {
+	var deriveCmd = &cli.Command{
+		Name:      "derive",
+		Usage:     "derive watchtower keys from a mnemonic seed into local keystore",
+		UsageText: "derive --seed <seedName> --count <N> --path <derivationPath>",
+		Flags: []cli.Flag{
+			&SeedNameFlag,
+			&CountFlag,
+			&StartIndexFlag,
+			&DerivationPathFlag,
+			&KeyPrefixFlag,
+			&KeyStoreType,
+			&InsecureFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return DeriveKeysCmd(cCtx)
+		},
+	}
+	return deriveCmd
+}
+
+func DeriveKeysCmd(cCtx *cli.Context) error {
+	seedName := cCtx.String("seed")
+	count := cCtx.Uint("count")
+	start := cCtx.Uint("start")
+	pathTemplate := cCtx.String("path")
+	prefix := cCtx.String("prefix")
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+
+	if prefix == "" {
+		prefix = seedName + "-"
+	}
+
+	// the last index must stay below the hardened range, and start+count
+	// must not wrap around
+	if uint64(start)+uint64(count) > uint64(HDHardenedKeyStart) {
+		return fmt.Errorf("--start %d and --count %d go past index %d: %w", start, count, HDHardenedKeyStart-1, ErrInvalidHDIndex)
+	}
+	if _, err := HDPathForIndex(pathTemplate, 0); err != nil {
+		return err
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	seed, err := LoadSeed(cCtx.Context, seedName)
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(seed)
+
+	for index := uint32(start); uint64(index) < uint64(start)+uint64(count); index++ {
+		if err := deriveKey(keyStore, keyType, seed, seedName, pathTemplate, prefix, index, insecure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deriveKey(keyStore KeyStore, keyType string, seed []byte, seedName string, pathTemplate string, prefix string, index uint32, insecure bool) error {
+	keyName := prefix + strconv.FormatUint(uint64(index), 10)
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if allow, err := AllowKeyStoreOverwrite(keyStore, keyName); err != nil || !allow {
+		return err
+	}
+
+	privateKey, err := DeriveKeyFromSeed(seed, pathTemplate, index)
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(privateKey)
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	if err := keyStore.Save(keyName, privateKey, insecure); err != nil {
+		return err
+	}
+	if err := RecordKeyMetadata(keyType, keyName, address, KeySourceDerived); err != nil {
+		return err
+	}
+	if _, err := UpdateKeyMetadata(keyType, keyName, KeyRoleWatchtower, []string{"seed:" + seedName}, nil); err != nil {
+		return err
+	}
+	path, _ := HDPathForIndex(pathTemplate, index)
+	fmt.Printf("Derived key: %s %s %s\n", keyName, address.Hex(), path)
+	return nil
+}
+
+// CreateMnemonicSeed stores an encrypted BIP-39 mnemonic, either typed in
+// by the user or freshly generated
+func CreateMnemonicSeed(ctx context.Context, seedName string, insecure bool) error {
+	if seedName == "" {
+		seedName = DefaultSeedName
+	}
+
+	if err := ValidateKeyName(seedName); err != nil {
+		return fmt.Errorf("Error validating seed name: %w", err)
+	}
+
+	seedPath := getSeedFile(seedName)
+	if !AllowKeyOverwrite(seedPath) {
+		return nil
+	}
+
+	fmt.Print("Enter mnemonic to import (leave empty to generate a new one): ")
+	input, err := ReadHiddenInput()
+	if err != nil {
+		return err
+	}
+	mnemonic := strings.Join(strings.Fields(string(input)), " ")
+	WipeBytes(input)
+
+	if mnemonic == "" {
+		entropy, err := bip39.NewEntropy(256)
+		if err != nil {
+			return fmt.Errorf("Error generating entropy: %w", err)
+		}
+
+		mnemonic, err = bip39.NewMnemonic(entropy)
+		if err != nil {
+			return fmt.Errorf("Error generating mnemonic: %w", err)
+		}
+
+		fmt.Println("Write down the following mnemonic and keep it safe, it will not be shown again:")
+		fmt.Println()
+		fmt.Println(mnemonic)
+		fmt.Println()
+	} else if !bip39.IsMnemonicValid(mnemonic) {
+		return fmt.Errorf("Error importing mnemonic: %w", ErrInvalidMnemonic)
+	}
+
+	// asked twice, as a mistyped password makes the seed unrecoverable
+	password, err := GetNewPasswordFromPrompt(ctx, insecure, "encrypt the seed")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(password)
+
+	mnemonicBytes := []byte(mnemonic)
+	defer WipeBytes(mnemonicBytes)
+
+	cryptoJson, err := keystore.EncryptDataV3(mnemonicBytes, password, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return fmt.Errorf("Error encrypting mnemonic: %w", err)
+	}
+
+	data, err := json.MarshalIndent(seedFile{Version: SeedFileVersion, Crypto: cryptoJson}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding seed file: %w", err)
+	}
+
+	if err := EnsureDirectory(m_seedDir); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(seedPath, data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created seed: %s\n", seedName)
+	return nil
+}
+
+// LoadSeed decrypts a stored mnemonic and returns its BIP-39 seed, the
+// caller wipes it once the keys are derived
+func LoadSeed(ctx context.Context, seedName string) ([]byte, error) {
+	if seedName == "" {
+		seedName = DefaultSeedName
+	}
+
+	data, err := os.ReadFile(getSeedFile(seedName))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading seed %s: %w", seedName, err)
+	}
+
+	var seed seedFile
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return nil, fmt.Errorf("Error parsing seed %s: %w", seedName, err)
+	}
+
+	password, err := GetPasswordFromPrompt(ctx, true, "unlock seed "+seedName)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(password)
+
+	mnemonic, err := keystore.DecryptDataV3(seed.Crypto, string(password))
+	if errors.Is(err, keystore.ErrDecrypt) {
+		err = ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting seed %s: %w", seedName, err)
+	}
+	defer WipeBytes(mnemonic)
+
+	return bip39.NewSeed(string(mnemonic), ""), nil
+}
+
+// HDPathForIndex replaces the trailing "/i" placeholder of a derivation
+// path template such as m/44'/60'/0'/0/i with index. A template without
+// the placeholder is refused, as the index would be glued to its last
+// component, and so is an index in the hardened range
+func HDPathForIndex(pathTemplate string, index uint32) (string, error) {
+	if pathTemplate == "" {
+		pathTemplate = DefaultHDPath
+	}
+	if !strings.HasSuffix(pathTemplate, "/i") {
+		return "", fmt.Errorf("derivation path %s: %w", pathTemplate, ErrInvalidHDPath)
+	}
+	if index >= HDHardenedKeyStart {
+		return "", fmt.Errorf("index %d: %w", index, ErrInvalidHDIndex)
+	}
+	return strings.TrimSuffix(pathTemplate, "i") + strconv.FormatUint(uint64(index), 10), nil
+}
+
+func DeriveKeyFromSeed(seed []byte, pathTemplate string, index uint32) (*ecdsa.PrivateKey, error) {
+	hdPath, err := HDPathForIndex(pathTemplate, index)
+	if err != nil {
+		return nil, err
+	}
+	path, err := accounts.ParseDerivationPath(hdPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing derivation path %s: %w", pathTemplate, err)
+	}
+
+	privateKey, err := deriveBIP32Key(seed, path)
+	if err != nil {
+		return nil, fmt.Errorf("Error deriving key %s: %w", path.String(), err)
+	}
+
+	return privateKey, nil
+}
+
+// deriveBIP32Key walks the BIP-32 private key derivation for path
+func deriveBIP32Key(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	curveOrder := crypto.S256().Params().N
+
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	chainCode := sum[32:]
+	if key.Sign() == 0 || key.Cmp(curveOrder) >= 0 {
+		return nil, ErrInvalidHDKey
+	}
+
+	for _, index := range path {
+		var data []byte
+		if index >= HDHardenedKeyStart {
+			data = append([]byte{0}, math.PaddedBigBytes(key, 32)...)
+		} else {
+			parent, err := crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+			if err != nil {
+				return nil, err
+			}
+			data = crypto.CompressPubkey(&parent.PublicKey)
+		}
+		data = binary.BigEndian.AppendUint32(data, index)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(curveOrder) >= 0 {
+			return nil, ErrInvalidHDKey
+		}
+
+		key = tweak.Add(tweak, key).Mod(tweak, curveOrder)
+		if key.Sign() == 0 {
+			return nil, ErrInvalidHDKey
+		}
+		chainCode = sum[32:]
+	}
+
+	return crypto.ToECDSA(math.PaddedBigBytes(key, 32))
+}
+
+func getSeedFile(seedName string) string {
+	return filepath.Join(m_seedDir, seedName+SeedSuffixName)
+}
@@ -0,0 +1,91 @@
+package wc_common
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip39"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// BIP-32 test vector 1
+func TestBIP32Derivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+
+	tests := []struct {
+		path accounts.DerivationPath
+		key  string
+	}{
+		{accounts.DerivationPath{}, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{accounts.DerivationPath{HDHardenedKeyStart}, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{accounts.DerivationPath{HDHardenedKeyStart, 1}, "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{accounts.DerivationPath{HDHardenedKeyStart, 1, HDHardenedKeyStart + 2}, "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+	}
+
+	for _, test := range tests {
+		key, err := deriveBIP32Key(seed, test.path)
+		if err != nil {
+			t.Fatalf("deriving %s: %v", test.path, err)
+		}
+		if got := hex.EncodeToString(crypto.FromECDSA(key)); got != test.key {
+			t.Errorf("key of %s = %s, want %s", test.path, got, test.key)
+		}
+	}
+}
+
+// addresses of the test mnemonic at m/44'/60'/0'/0/i, as other wallets
+// derive them
+func TestDeriveKeyFromSeed(t *testing.T) {
+	if !bip39.IsMnemonicValid(testMnemonic) {
+		t.Fatalf("mnemonic %q is not valid", testMnemonic)
+	}
+	seed := bip39.NewSeed(testMnemonic, "")
+
+	tests := []struct {
+		index   uint32
+		address string
+	}{
+		{0, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"},
+		{1, "0x6Fac4D18c912343BF86fa7049364Dd4E424Ab9C0"},
+		{2, "0xb6716976A3ebe8D39aCEB04372f22Ff8e6802D7A"},
+		{3, "0xF3f50213C1d2e255e4B2bAD430F8A38EEF8D718E"},
+		{4, "0x51cA8ff9f1C0a99f88E86B8112eA3237F55374cA"},
+	}
+
+	for _, test := range tests {
+		key, err := DeriveKeyFromSeed(seed, "m/44'/60'/0'/0/i", test.index)
+		if err != nil {
+			t.Fatalf("deriving index %d: %v", test.index, err)
+		}
+		if address := crypto.PubkeyToAddress(key.PublicKey).Hex(); address != test.address {
+			t.Errorf("address of index %d = %s, want %s", test.index, address, test.address)
+		}
+	}
+
+	if _, err := DeriveKeyFromSeed(seed, DefaultHDPath, HDHardenedKeyStart); !errors.Is(err, ErrInvalidHDIndex) {
+		t.Errorf("deriving a hardened index error = %v, want ErrInvalidHDIndex", err)
+	}
+}
+
+func TestHDPathForIndex(t *testing.T) {
+	path, err := HDPathForIndex("m/44'/60'/0'/0/i", 5)
+	if err != nil || path != "m/44'/60'/0'/0/5" {
+		t.Errorf("HDPathForIndex = %q, %v", path, err)
+	}
+
+	for _, template := range []string{"m/44'/60'/0'/0", "m/44'/60'/0'/0i", "m/44'/60'/0'/0/i/1"} {
+		if _, err := HDPathForIndex(template, 5); !errors.Is(err, ErrInvalidHDPath) {
+			t.Errorf("HDPathForIndex(%q) error = %v, want ErrInvalidHDPath", template, err)
+		}
+	}
+
+	for _, index := range []uint32{HDHardenedKeyStart, HDHardenedKeyStart + 1, 1<<32 - 1} {
+		if _, err := HDPathForIndex("m/44'/60'/0'/0/i", index); !errors.Is(err, ErrInvalidHDIndex) {
+			t.Errorf("HDPathForIndex(%d) error = %v, want ErrInvalidHDIndex", index, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package wc_common
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+// ImportKeyFromFileCmd imports a key from a geth/clef V3 keystore file, a
+// file holding a hex key, or stdin when the file is "-"
+func ImportKeyFromFileCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
+	fromFile := cCtx.String("from-file")
+
+	privateKey, err := ReadPrivateKeyFile(fromFile, cCtx.String("key-password-file"))
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(privateKey)
+	address := GetPublicAddressFromPrivateKey(privateKey)
+	fmt.Printf("Read key for address: %s\n", address.Hex())
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+
+	if allow, err := AllowKeyStoreOverwrite(keyStore, keyName); err != nil || !allow {
+		return err
+	}
+
+	if err := keyStore.Save(keyName, privateKey, insecure); err != nil {
+		return err
+	}
+	if err := RecordKeyMetadata(keyType, keyName, address, KeySourceImported); err != nil {
+		return err
+	}
+	if _, err := UpdateKeyMetadata(keyType, keyName, cCtx.String("role"), cCtx.StringSlice("label"), nil); err != nil {
+		return err
+	}
+	fmt.Printf("Imported key: %s %s\n", keyName, address.Hex())
+	return nil
+}
+
+// ReadPrivateKeyFile reads and validates a private key from path. A V3
+// keystore file is decrypted with its own password, read from
+// passwordFile when given
+func ReadPrivateKeyFile(path string, passwordFile string) (*ecdsa.PrivateKey, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key file: %w", err)
+	}
+	defer WipeBytes(data)
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		privateKey, err := ParsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing hex key: %w", err)
+		}
+		return privateKey, nil
+	}
+
+	var password []byte
+	if passwordFile != "" {
+		source, err := readPasswordFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = source.password
+	} else {
+		fmt.Print("Enter password to decrypt the V3 keystore file: ")
+		if password, err = ReadHiddenInput(); err != nil {
+			return nil, err
+		}
+	}
+	defer WipeBytes(password)
+
+	key, err := keystore.DecryptKey(data, string(password))
+	if errors.Is(err, keystore.ErrDecrypt) {
+		err = ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting V3 keystore file: %w", err)
+	}
+
+	return key.PrivateKey, nil
+}
+
+// ParsePrivateKey validates a hex encoded private key, with or without the
+// 0x prefix. hexKey is left for the caller to wipe
+func ParsePrivateKey(hexKey []byte) (*ecdsa.PrivateKey, error) {
+	hexKey = bytes.TrimPrefix(bytes.TrimSpace(hexKey), []byte("0x"))
+	if len(hexKey) == 0 {
+		return nil, ErrEmptyPrivateKey
+	}
+
+	keyBytes := make([]byte, hex.DecodedLen(len(hexKey)))
+	defer WipeBytes(keyBytes)
+
+	if _, err := hex.Decode(keyBytes, hexKey); err != nil {
+		return nil, err
+	}
+	return crypto.ToECDSA(keyBytes)
+}
@@ -1,49 +1,57 @@
 package wc_common
 
 import (
+	"context"
 	"crypto/ecdsa"
-	"encoding/hex"
-	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"regexp"
-	"strings"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/urfave/cli/v2"
-
-	sdkEcdsa "github.com/Layr-Labs/eigensdk-go/crypto/ecdsa"
 )
 
-const W3SECRETPASSPHRASE = "W3SECRETPASSPHRASE"
-
 var m_useEncryptedKeys bool = false
 var m_isFullPath bool = false
 var m_retryMounting bool = false
 
-var m_gocryptfsDirName string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, GoCryptFSDirName)
-var m_gocryptfsEncDir string = filepath.Join(m_gocryptfsDirName, GocryptfsEncDirName)
-var m_gocryptfsDecDir string = filepath.Join(m_gocryptfsDirName, GocryptfsDecDirName)
-var m_goCryptFSConfig string = filepath.Join(m_gocryptfsEncDir, GoCryptFSConfigName)
-
-var m_w3SecretKeyDir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, W3SecretKeyDirName)
-var m_w3SecretKeysPassword string = ""
-
+// KeysCmd takes the keys subcommands that need to talk to the chain as
+// extraCmds, as those live in the commands package. An extra command with
+// the name of a built-in one replaces it
+func KeysCmd(extraCmds ...*cli.Command) *cli.Command {
+	subcommands := []*cli.Command{
+		InitCmd(),
+		CreateCmd(),
+		ImportCmd(),
+		ShowCmd(),
+		ExportCmd(),
+		DeleteCmd(),
+		ListCmd(),
+		DeriveCmd(),
+		BackupCmd(),
+		RestoreCmd(),
+		ArchiveCmd(),
+		PasswdCmd(),
+		MigrateCmd(),
+		LabelCmd(),
+	}
+
+	for _, extraCmd := range extraCmds {
+		replaced := false
+		for i, cmd := range subcommands {
+			if cmd.Name == extraCmd.Name {
+				subcommands[i] = extraCmd
+				replaced = true
+			}
+		}
+		if !replaced {
+			subcommands = append(subcommands, extraCmd)
+		}
+	}
 
-func KeysCmd() *cli.Command {
 	var keysCmd = &cli.Command{
-		Name:  "keys",
-		Usage: "Manage the operator's keys",
-		Subcommands: []*cli.Command{
-			InitCmd(),
-			CreateCmd(),
-			ImportCmd(),
-			ExportCmd(),
-			DeleteCmd(),
-			ListCmd(),
-		},
+		Name:        "keys",
+		Usage:       "Manage the operator's keys",
+		Subcommands: subcommands,
 	}
 	return keysCmd
 }
@@ -58,8 +66,7 @@ func InitCmd() *cli.Command {
 			&KeyStoreType,
 		},
 		Action: func(cCtx *cli.Context) error {
-			InitKeyStore(cCtx)
-			return nil
+			return InitKeyStore(cCtx)
 		},
 	}
 	return initCmd
@@ -74,10 +81,17 @@ func CreateCmd() *cli.Command {
 			&KeyNameFlag,
 			&KeyStoreType,
 			&InsecureFlag,
+			&MnemonicFlag,
+			&NewPasswordFileFlag,
+			&CountFlag,
+			&StartIndexFlag,
+			&KeyPrefixFlag,
+			&WriteConfigFlag,
+			&RoleFlag,
+			&LabelFlag,
 		},
 		Action: func(cCtx *cli.Context) error {
-			CreateKeyCmd(cCtx)
-			return nil
+			return CreateKeyCmd(cCtx)
 		},
 	}
 	return createCmd
@@ -92,10 +106,13 @@ func ImportCmd() *cli.Command {
 			&KeyNameFlag,
 			&KeyStoreType,
 			&InsecureFlag,
+			&FromFileFlag,
+			&KeyPasswordFileFlag,
+			&RoleFlag,
+			&LabelFlag,
 		},
 		Action: func(cCtx *cli.Context) error {
-			ImportKeyCmd(cCtx)
-			return nil
+			return ImportKeyCmd(cCtx)
 		},
 	}
 
@@ -113,15 +130,32 @@ func DeleteCmd() *cli.Command {
 		},
 		Action: func(cCtx *cli.Context) error {
 			if cCtx.String("key-name") == "" {
-				CheckError(ErrEmptyKeyName, "Required flag \"key-name\" not set")
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
 			}
-			DeleteKeyCmd(cCtx)
-			return nil
+			return DeleteKeyCmd(cCtx)
 		},
 	}
 	return deleteCmd
 }
 
+func PasswdCmd() *cli.Command {
+	var passwdCmd = &cli.Command{
+		Name:      "passwd",
+		Usage:     "change the password of a key, or of every key when no key name is given",
+		UsageText: "passwd [--key-name <keyName>]",
+		Flags: []cli.Flag{
+			&KeyNameFlag,
+			&KeyStoreType,
+			&InsecureFlag,
+			&NewPasswordFileFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return ChangePasswordCmd(cCtx)
+		},
+	}
+	return passwdCmd
+}
+
 func ListCmd() *cli.Command {
 	var listCmd = &cli.Command{
 		Name:      "list",
@@ -131,162 +165,142 @@ func ListCmd() *cli.Command {
 			&KeyStoreType,
 		},
 		Action: func(cCtx *cli.Context) error {
-			ListKeyCmd(cCtx)
-			return nil
+			return ListKeyCmd(cCtx)
 		},
 	}
 	return listCmd
 }
 
-func InitKeyStore(cCtx *cli.Context) {
+func InitKeyStore(cCtx *cli.Context) error {
 	insecure := cCtx.Bool("insecure")
 	keyType := cCtx.String("key-type")
 
-	//Use enums instead of strings
-	//Nested directory creation. Check full path
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		CheckIfGocryptfsIsInstalled()
-		if !DirectoryExists(GoCryptFSDirName) {
-			CreateDirectory(GoCryptFSDirName)
-		}
-		if !DirectoryExists(m_gocryptfsEncDir) {
-			CreateDirectory(m_gocryptfsEncDir)
-		}
-
-		if !DirectoryExists(m_gocryptfsDecDir) {
-			CreateDirectory(m_gocryptfsDecDir)
-		}
-		InitGocryptfs(insecure)
-	case KeyTypeW3SecretKey:
-		if !DirectoryExists(m_w3SecretKeyDir) {
-			CreateDirectory(m_w3SecretKeyDir)
-		}
-		fmt.Println("Init keystore done")
-	default:
-		CheckError(ErrInvalidKeyType, "Error initializing key store : "+keyType)
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
 	}
+	return keyStore.Init(insecure)
 }
 
-func CreateKeyCmd(cCtx *cli.Context) {
+func CreateKeyCmd(cCtx *cli.Context) error {
 	keyName := cCtx.String("key-name")
 	keyType := cCtx.String("key-type")
 	insecure := cCtx.Bool("insecure")
 
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		ValidateAndMount()
-		CreateGoCryptfsKey(keyName)
-	case KeyTypeW3SecretKey:
-		CreateW3SecretKey(keyName, insecure)
-	default:
-		CheckError(ErrInvalidKeyType, "Error creating key : "+keyType)
+	if cCtx.Bool("mnemonic") {
+		return CreateMnemonicSeed(cCtx.Context, keyName, insecure)
+	}
+
+	if cCtx.Uint("count") > 1 || cCtx.String("prefix") != "" || cCtx.String("write-config") != "" {
+		return CreateKeysCmd(cCtx)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
 	}
+
+	keyName, err = keyStore.Create(keyName, insecure)
+	if err != nil || keyName == "" {
+		return err
+	}
+	_, err = UpdateKeyMetadata(keyType, keyName, cCtx.String("role"), cCtx.StringSlice("label"), nil)
+	return err
 }
 
-func ImportKeyCmd(cCtx *cli.Context) {
+func ImportKeyCmd(cCtx *cli.Context) error {
 	keyName := cCtx.String("key-name")
 	keyType := cCtx.String("key-type")
 	insecure := cCtx.Bool("insecure")
 
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		ValidateAndMount()
-		ImportGoCryptfsKey(keyName)
-	case KeyTypeW3SecretKey:
-		ImportW3SecretKey(keyName, insecure)
-	default:
-		CheckError(ErrInvalidKeyType, "Error importing key : "+keyType)
+	if cCtx.String("from-file") != "" {
+		return ImportKeyFromFileCmd(cCtx)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
 	}
+
+	keyName, err = keyStore.Import(keyName, insecure)
+	if err != nil || keyName == "" {
+		return err
+	}
+	_, err = UpdateKeyMetadata(keyType, keyName, cCtx.String("role"), cCtx.StringSlice("label"), nil)
+	return err
 }
 
 func ExportCmd() *cli.Command {
 	var exportCmd = &cli.Command{
 		Name:      "export",
 		Usage:     "export existing key from local keystore",
-		UsageText: "export <keyName>",
+		UsageText: "export --key-name <keyName> --reveal-private-key",
 		Flags: []cli.Flag{
 			&KeyNameFlag,
 			&KeyStoreType,
+			&RevealPrivateKeyFlag,
 		},
 		Action: func(cCtx *cli.Context) error {
 			if cCtx.String("key-name") == "" {
-				CheckError(ErrEmptyKeyName, "Required flag \"key-name\" not set")
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
 			}
-			ExportKeyCmd(cCtx)
-			return nil
+			return ExportKeyCmd(cCtx)
 		},
 	}
 
 	return exportCmd
 }
 
-func DeleteKeyCmd(cCtx *cli.Context) {
+func DeleteKeyCmd(cCtx *cli.Context) error {
 	keyName := cCtx.String("key-name")
 	keyType := cCtx.String("key-type")
 
-	err := ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
 
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		ValidateAndMount()
-		DeleteGoCryptfsKey(keyName)
-	case KeyTypeW3SecretKey:
-		DeleteW3SecretKey(keyName)
-	default:
-		CheckError(ErrInvalidKeyType, "Error deleting key : "+keyType)
+	if err := keyStore.Delete(keyName); err != nil {
+		return err
+	}
+	if err := DeleteKeyMetadata(keyType, keyName); err != nil {
+		return err
 	}
 
 	fmt.Printf("Deleted key: %s\n", keyName)
+	return nil
 }
 
-func ListKeyCmd(cCtx *cli.Context) {
+func ChangePasswordCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
 	keyType := cCtx.String("key-type")
+	insecure := cCtx.Bool("insecure")
 
-	var err error = nil
-	var dir *os.File
-	var path string
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		dir, err = os.Open(m_gocryptfsEncDir)
-		path, _ = filepath.Abs(m_gocryptfsEncDir)
-	case KeyTypeW3SecretKey:
-		dir, err = os.Open(m_w3SecretKeyDir)
-		path, _ = filepath.Abs(m_w3SecretKeyDir)
-	default:
-		CheckErrorWithoutUnmount(ErrInvalidKeyType, "error listing keys")
-	}
-
-	CheckError(err, "Error opening directory")
-	defer dir.Close()
-
-	files, err := dir.Readdir(-1)
-	CheckError(err, "Error reading directory")
-
-	separatorLen := len(path) + 100
-	nameLen := len(path) + 75
-	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
-	fmt.Printf("   %-*s %-25s\n", nameLen, "Name", "Created")
-	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
-
-	for _, file := range files {
-		if file.Name() == GoCryptFSConfigName {
-			continue
+	if keyName != "" {
+		if err := ValidateKeyName(keyName); err != nil {
+			return fmt.Errorf("Error validating key name: %w", err)
 		}
-
-		createdTime := file.ModTime().Format("02-01-2006 15:04:05")
-
-		fmt.Printf("   %-*s %-25s\n", nameLen, filepath.Join(path, file.Name()), createdTime)
 	}
 
-	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+	return keyStore.ChangePassword(keyName, insecure)
 }
 
-func InitGocryptfs(insecure bool) {
-	initCmd := exec.Command("gocryptfs", "-init", "-plaintextnames", m_gocryptfsEncDir)
+func ListKeyCmd(cCtx *cli.Context) error {
+	keyType := cCtx.String("key-type")
 
-	RunCommandWithPassword(initCmd, "init", insecure)
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
+	}
+	return keyStore.List()
 }
 
 func ValidateKeyName(keyName string) error {
@@ -300,306 +314,74 @@ func ValidateKeyName(keyName string) error {
 	return nil
 }
 
-func ExportKeyCmd(cCtx *cli.Context) {
+func ExportKeyCmd(cCtx *cli.Context) error {
 	keyName := cCtx.String("key-name")
 	keyType := cCtx.String("key-type")
 
-	err := ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
-
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		ValidateAndMount()
-		ExportGoCryptfsKey(keyName)
-	case KeyTypeW3SecretKey:
-		ExportW3SecretKey(keyName)
-	default:
-		var err error
-		err = errors.New("invalid key type")
-		CheckError(err, "error exporting key")
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
 	}
 
-	fmt.Printf("Exported key: %s\n", keyName)
-}
-
-func GetPrivateKeyFromUser() string {
-	fmt.Print("Enter private key: ")
-	return ReadHiddenInput()
-}
-
-func CreateGoCryptfsKey(keyName string) {
-	privateKey := GenerateRandomKey()
-
-	address := GetPublicAddressFromPrivateKey(privateKey)
-
-	if keyName == "" {
-		keyName = address.String()
-	}
-
-	err := ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
-
-	keyFile := filepath.Join(m_gocryptfsDecDir, keyName)
-
-	if !AllowKeyOverwrite(keyFile) {
-		return
+	if !cCtx.Bool("reveal-private-key") {
+		return fmt.Errorf("Error exporting key %s: %w", keyName, ErrRevealNotConfirmed)
 	}
 
-	privateKeyHex := hex.EncodeToString(privateKey.D.Bytes())
-	CreateKeyFileAndStoreKey(keyFile, privateKeyHex)
-
-	fmt.Printf("Created key: %s\n", keyName)
-}
-
-func CreateKeyFileAndStoreKey(keyFile string, privateKey string) {
-	file, err := os.Create(keyFile)
-	CheckError(err, "Error creating file")
-	defer file.Close()
-
-	_, err = file.WriteString(privateKey)
-	CheckError(err, "Error writing to file")
-}
-
-func CreateW3SecretKey(keyName string, insecure bool) {
-	password := GetPasswordFromPrompt(insecure, "create")
-	privateKey := GenerateRandomKey()
-
-	address := GetPublicAddressFromPrivateKey(privateKey)
-
-	if keyName == "" {
-		keyName = address.String()
-	}
-
-	err := ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
-
-	keyFileName := keyName + W3SecretKeySuffixName
-	keyFile := filepath.Join(m_w3SecretKeyDir, keyFileName)
-
-	if !AllowKeyOverwrite(keyFile) {
-		return
-	}
-
-	err = sdkEcdsa.WriteKey(keyFile, privateKey, password)
-	CheckError(err, "Error Writing ecdsa key")
-
-	fmt.Printf("Created key: %s\n", keyName)
-}
-
-func ImportGoCryptfsKey(keyName string) {
-	privateKey := GetPrivateKeyFromUser()
-	privateKey = strings.TrimPrefix(privateKey, "0x")
-
-	privKeyBytes, err := hex.DecodeString(privateKey)
-	CheckError(err, "Error decoding private key hex string")
-
-	privKey, err := crypto.ToECDSA(privKeyBytes)
-	CheckError(err, "Error converting bytes to ECDSA private key")
-
-	address := GetPublicAddressFromPrivateKey(privKey)
-
-	if keyName == "" {
-		keyName = address.String()
-	}
-
-	err = ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
-
-	keyFile := filepath.Join(m_gocryptfsDecDir, keyName)
-
-	if !AllowKeyOverwrite(keyFile) {
-		return
+	keyStore, err := GetKeyStore(cCtx.Context, keyType)
+	if err != nil {
+		return err
 	}
 
-	CreateKeyFileAndStoreKey(keyFile, privateKey)
-	fmt.Printf("Imported key: %s\n", keyName)
-}
-
-func ImportW3SecretKey(keyName string, insecure bool) {
-	password := GetPasswordFromPrompt(insecure, "import")
-
-	privateKey := GetPrivateKeyFromUser()
-	privateKey = strings.TrimPrefix(privateKey, "0x")
-	privateKeyPair, _ := crypto.HexToECDSA(privateKey)
-	address := GetPublicAddressFromPrivateKey(privateKeyPair)
-
-	if keyName == "" {
-		keyName = address.String()
+	if err := keyStore.Export(keyName); err != nil {
+		return err
 	}
 
-	err := ValidateKeyName(keyName)
-	CheckError(err, "Error validating key name")
-
-	keyFileName := keyName + W3SecretKeySuffixName
-	keyFile := filepath.Join(m_w3SecretKeyDir, keyFileName)
-
-	if !AllowKeyOverwrite(keyFile) {
-		return
-	}
-
-	err = sdkEcdsa.WriteKey(keyFile, privateKeyPair, password)
-	CheckError(err, "Error Writing ecdsa key")
-	fmt.Printf("Imported key: %s\n", keyName)
-}
-
-func ExportGoCryptfsKey(keyName string) {
-	privateKey := GetGocryptfsPrivateKey(keyName)
-	_, publicKey := GetECDSAPrivateAndPublicKey(privateKey)
-
-	fmt.Println("Public key : ", publicKey)
-	fmt.Println("Private key : ", privateKey)
-}
-
-func ExportW3SecretKey(keyName string) {
-	password := GetPasswordFromPrompt(true, "export")
-
-	key, err := sdkEcdsa.ReadKey(GetSanitizedW3SecretKeyName(keyName), password)
-	CheckError(err, "Error reading ecdsa key")
-
-	privateKey := hex.EncodeToString(key.D.Bytes())
-
-	fmt.Println("Public key : ", GetPublicAddressFromPrivateKey(key))
-	fmt.Println("Private key : ", privateKey)
-}
-
-func DeleteGoCryptfsKey(keyName string) {
-	err := os.Remove(GetSanitizedGocryptfsKeyName(keyName))
-	CheckError(err, "Error deleting key\n")
-}
-
-func DeleteW3SecretKey(keyName string) {
-	err := os.Remove(GetSanitizedW3SecretKeyName(keyName))
-	CheckError(err, "Error deleting key\n")
-}
-
-func ValidEncryptedDir() bool {
-	_, err := os.Stat(m_goCryptFSConfig)
-
-	return !os.IsNotExist(err)
-}
-
-func GetGocryptfsPrivateKey(keyName string) string {
-	keyFile := GetSanitizedGocryptfsKeyName(keyName)
-	data, err := os.ReadFile(keyFile)
-	CheckError(err, "Error reading key file "+keyFile)
-	return string(data)
+	fmt.Printf("Exported key: %s\n", keyName)
+	return nil
 }
 
-func GetW3SecretStoragePrivateKey(keyName string) string {
-	w3secretPassphrase := os.Getenv(W3SECRETPASSPHRASE)
-	if len(w3secretPassphrase) != 0 {
-		m_w3SecretKeysPassword = w3secretPassphrase
-		os.Unsetenv(W3SECRETPASSPHRASE)
-	}
-
-	if m_w3SecretKeysPassword == "" {
-		m_w3SecretKeysPassword = GetPasswordFromPrompt(true, "export web3 secret storage keys")
-	}
-
-	key, err := sdkEcdsa.ReadKey(GetSanitizedW3SecretKeyName(keyName), m_w3SecretKeysPassword)
-	CheckError(err, "Error reading ecdsa key")
-
-	privateKey := hex.EncodeToString(key.D.Bytes())
-
-	return privateKey
+// GetPrivateKeyFromUser reads a hex key from the terminal, the caller
+// wipes it once parsed
+func GetPrivateKeyFromUser() ([]byte, error) {
+	fmt.Print("Enter private key: ")
+	return ReadHiddenInput()
 }
 
 func UseEncryptedKeys(keyType string) {
-	if m_useEncryptedKeys {
-		return
-	}
-
 	m_useEncryptedKeys = true
-	if keyType == KeyTypeGoCryptFS {
-		ValidateAndMount()
-	}
 }
 
-func ProcessConfigKeyPath(keyPath string, keyType string) {
-	dir, file := filepath.Split(keyPath)
-
-	switch keyType {
-	case KeyTypeGoCryptFS:
-		if file != keyPath {
-			// go to the grand parent directory of the key path to get the .encrypted_keys path
-			parentPathGoCryptFS := filepath.Dir(filepath.Dir(dir))
-			m_gocryptfsEncDir = filepath.Join(parentPathGoCryptFS, GocryptfsEncDirName)
-			m_gocryptfsDecDir = filepath.Join(parentPathGoCryptFS, GocryptfsDecDirName)
-			m_goCryptFSConfig = filepath.Join(m_gocryptfsEncDir, GoCryptFSConfigName)
-			m_isFullPath = true
-		}
-		fmt.Printf("Using the key path : %s\n", m_gocryptfsEncDir)
-
-	case KeyTypeW3SecretKey:
-		if file != keyPath {
-			m_w3SecretKeyDir = dir
-			m_isFullPath = true
-		}
-		fmt.Printf("Using the key path : %s\n", m_w3SecretKeyDir)
-
-	default:
-		CheckError(ErrInvalidKeyType, "Error processing key path : "+keyType)
+func ProcessConfigKeyPath(ctx context.Context, keyPath string, keyType string) error {
+	keyStore, err := GetKeyStore(ctx, keyType)
+	if err != nil {
+		return err
 	}
-
+	keyStore.UseKeyPath(keyPath)
+	return nil
 }
 
 func RetryMounting() {
 	m_retryMounting = true
 }
 
-func GetPrivateKey(key string, keyType string) string {
-	if m_useEncryptedKeys {
-		keyName := key
-		if m_isFullPath {
-			_, keyName = filepath.Split(key)
-		}
-
-		switch keyType {
-		case KeyTypeGoCryptFS:
-			return GetGocryptfsPrivateKey(keyName)
-		case KeyTypeW3SecretKey:
-			return GetW3SecretStoragePrivateKey(keyName)
-		default:
-			CheckError(ErrInvalidKeyType, "Error processing key path : "+keyType)
-		}
-	}
-	return key
-}
-
-func GenerateRandomKey() *ecdsa.PrivateKey {
+func GenerateRandomKey() (*ecdsa.PrivateKey, error) {
 	privateKey, err := crypto.GenerateKey()
-	CheckError(err, "Error generating key")
-
-	return privateKey
-}
-
-func LoadPrivateKey(path string, keyType string) (*ecdsa.PrivateKey, error) {
-	priv, err := crypto.HexToECDSA(GetPrivateKey(path, keyType))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Error generating key: %w", err)
 	}
-	return priv, nil
-}
 
-func GetSanitizedW3SecretKeyName(keyName string) string {
-	keyFileName := keyName
-	if len(filepath.Ext(keyName)) == 0 {
-		keyFileName = keyName + W3SecretKeySuffixName
-	}
-
-	keyFile := keyFileName
-	if !filepath.IsAbs(keyFile) {
-		keyFile = filepath.Join(m_w3SecretKeyDir, keyFileName)
-	}
-
-	return keyFile
+	return privateKey, nil
 }
 
-func GetSanitizedGocryptfsKeyName(keyName string) string {
-	keyFile := keyName
-	if !filepath.IsAbs(keyFile) {
-		keyFile = filepath.Join(m_gocryptfsDecDir, keyName)
+// LoadPrivateKey decrypts the key at path from the keystore when the
+// config uses encrypted keys, and parses path as a hex key otherwise
+func LoadPrivateKey(ctx context.Context, path string, keyType string) (*ecdsa.PrivateKey, error) {
+	if !m_useEncryptedKeys {
+		return ParsePrivateKey([]byte(path))
 	}
 
-	return keyFile
+	keyStore, err := GetKeyStore(ctx, keyType)
+	if err != nil {
+		return nil, err
+	}
+	return keyStore.Load(path)
 }
@@ -0,0 +1,314 @@
+package wc_common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// KeyStore is implemented by every local keystore backend that can be
+// selected with --key-type or the encrypted_key_type config field
+type KeyStore interface {
+	// Init prepares the keystore on disk, it only needs to be done once
+	Init(insecure bool) error
+	// Create and Import return the name the key was stored under, or an
+	// empty name when the user kept an existing key
+	Create(keyName string, insecure bool) (string, error)
+	Import(keyName string, insecure bool) (string, error)
+	Export(keyName string) error
+	Delete(keyName string) error
+	List() error
+	// Load decrypts the private key for a key name or path, the caller
+	// wipes it with WipePrivateKey once it is not needed anymore
+	Load(keyPath string) (*ecdsa.PrivateKey, error)
+	// Save stores privateKey under keyName, replacing any existing key
+	// with that name, without prompting for anything but the password
+	Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error
+	// KeyNames returns the names of all the keys in the keystore
+	KeyNames() ([]string, error)
+	// ChangePassword re-encrypts keyName, or every key when keyName is
+	// empty, with a new password. An interrupted change must leave every
+	// key readable with either the old or the new password
+	ChangePassword(keyName string, insecure bool) error
+	// UseKeyPath points the keystore at the directory of a key path
+	// taken from the config file
+	UseKeyPath(keyPath string)
+	// KeyPath returns the path of keyName as referenced in the config
+	// file, the reverse of UseKeyPath
+	KeyPath(keyName string) string
+	// Lock wipes the password and the keys the keystore keeps in memory
+	Lock()
+}
+
+// KeySigner is implemented by keystores whose private keys can't be
+// loaded, like PKCS#11 tokens. Their keys sign 32 byte hashes in place, the
+// signature is [R || S || V] with V as 0/1
+type KeySigner interface {
+	PublicKey(keyName string) (*ecdsa.PublicKey, error)
+	SignHash(keyName string, hash []byte) ([]byte, error)
+}
+
+// KeyStoreOptions is passed to the constructor of a keystore, it carries
+// the secrets the keystore may ask for instead of package state
+type KeyStoreOptions struct {
+	Passwords *Passwords
+}
+
+// NewKeyStoreFunc returns a locked keystore, every call a new one
+type NewKeyStoreFunc func(options KeyStoreOptions) KeyStore
+
+// m_keyStoreTypes only holds constructors, the keystores holding passwords
+// and keys live in the KeyStores of a command
+var m_keyStoreTypes = map[string]NewKeyStoreFunc{}
+
+func RegisterKeyStore(keyType string, newKeyStore NewKeyStoreFunc) {
+	m_keyStoreTypes[keyType] = newKeyStore
+}
+
+// KeyStores opens the keystores of one command run. Each key type is
+// opened once, so its password is asked once, and Lock wipes the
+// passwords and keys of all of them when the command returns
+type KeyStores struct {
+	options KeyStoreOptions
+	opened  map[string]KeyStore
+}
+
+type keyStoresContextKey struct{}
+
+func NewKeyStores(passwords *Passwords) *KeyStores {
+	return &KeyStores{
+		options: KeyStoreOptions{Passwords: passwords},
+		opened:  map[string]KeyStore{},
+	}
+}
+
+// WithKeyStores returns a copy of ctx that carries keyStores
+func WithKeyStores(ctx context.Context, keyStores *KeyStores) context.Context {
+	return context.WithValue(ctx, keyStoresContextKey{}, keyStores)
+}
+
+// KeyStoresFromContext returns the keystores of the command of ctx. A
+// context without them, as used by the SDK, gets keystores that prompt
+// for every password and are never reused
+func KeyStoresFromContext(ctx context.Context) *KeyStores {
+	if ctx != nil {
+		if keyStores, ok := ctx.Value(keyStoresContextKey{}).(*KeyStores); ok {
+			return keyStores
+		}
+	}
+	return NewKeyStores(nil)
+}
+
+func (keyStores *KeyStores) Get(keyType string) (KeyStore, error) {
+	if keyStore, ok := keyStores.opened[keyType]; ok {
+		return keyStore, nil
+	}
+
+	newKeyStore, ok := m_keyStoreTypes[keyType]
+	if !ok {
+		return nil, fmt.Errorf("Error getting key store %s: %w", keyType, ErrInvalidKeyType)
+	}
+	keyStore := newKeyStore(keyStores.options)
+	keyStores.opened[keyType] = keyStore
+	return keyStore, nil
+}
+
+func (keyStores *KeyStores) Passwords() *Passwords {
+	return keyStores.options.Passwords
+}
+
+// Lock locks every opened keystore and wipes the passwords
+func (keyStores *KeyStores) Lock() {
+	for _, keyStore := range keyStores.opened {
+		keyStore.Lock()
+	}
+	keyStores.opened = map[string]KeyStore{}
+	keyStores.options.Passwords.Wipe()
+}
+
+func GetKeyStore(ctx context.Context, keyType string) (KeyStore, error) {
+	return KeyStoresFromContext(ctx).Get(keyType)
+}
+
+// GetKeySigner returns the keystore of keyType when its keys sign in place
+// instead of being loaded
+func GetKeySigner(ctx context.Context, keyType string) (KeySigner, bool) {
+	keyStore, err := GetKeyStore(ctx, keyType)
+	if err != nil {
+		return nil, false
+	}
+	keySigner, ok := keyStore.(KeySigner)
+	return keySigner, ok
+}
+
+// LoadPublicKey returns the public key of keyName, the private key is only
+// decrypted when the keystore can't sign in place
+func LoadPublicKey(keyStore KeyStore, keyName string) (*ecdsa.PublicKey, error) {
+	if keySigner, ok := keyStore.(KeySigner); ok {
+		return keySigner.PublicKey(keyName)
+	}
+
+	privateKey, err := keyStore.Load(keyName)
+	if err != nil {
+		return nil, err
+	}
+	defer WipePrivateKey(privateKey)
+
+	publicKey := privateKey.PublicKey
+	return &publicKey, nil
+}
+
+// GetKeyAddress reads the address from the key metadata, the key is only
+// decrypted when it has none. keyName may also be the path of the key, as
+// config files reference keys
+func GetKeyAddress(keyStore KeyStore, keyType string, keyName string) (common.Address, error) {
+	metadataName := keyName
+	if keyNames, err := keyStore.KeyNames(); err == nil {
+		for _, name := range keyNames {
+			if keyStore.KeyPath(name) == keyName {
+				metadataName = name
+			}
+		}
+	}
+
+	metadata, ok, err := GetKeyMetadata(keyType, metadataName)
+	if err != nil || ok {
+		return metadata.Address, err
+	}
+
+	publicKey, err := LoadPublicKey(keyStore, keyName)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+func GetKeyStoreTypes() []string {
+	keyTypes := make([]string, 0, len(m_keyStoreTypes))
+	for keyType := range m_keyStoreTypes {
+		keyTypes = append(keyTypes, keyType)
+	}
+	sort.Strings(keyTypes)
+	return keyTypes
+}
+
+// KeyListEntry is a row printed by the keys list command
+type KeyListEntry struct {
+	Name    string
+	Path    string
+	Created time.Time
+}
+
+// ListKeyDir lists every file of keyDir as a key, keySuffix is trimmed from
+// the file name to get the key name
+func ListKeyDir(keyType string, keyDir string, keySuffix string, skipFiles ...string) error {
+	dir, err := os.Open(keyDir)
+	if err != nil {
+		return fmt.Errorf("Error opening directory: %w", err)
+	}
+	defer dir.Close()
+
+	path, _ := filepath.Abs(keyDir)
+
+	files, err := dir.Readdir(-1)
+	if err != nil {
+		return fmt.Errorf("Error reading directory: %w", err)
+	}
+
+	var keys []KeyListEntry
+	for _, file := range files {
+		if isSkippedFile(file.Name(), skipFiles) {
+			continue
+		}
+
+		keys = append(keys, KeyListEntry{
+			Name:    strings.TrimSuffix(file.Name(), keySuffix),
+			Path:    filepath.Join(path, file.Name()),
+			Created: file.ModTime(),
+		})
+	}
+
+	return PrintKeyList(keyType, path, keys)
+}
+
+// PrintKeyList prints the keys with the metadata recorded for them, keys
+// stored before metadata was recorded show only their path
+func PrintKeyList(keyType string, path string, keys []KeyListEntry) error {
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return err
+	}
+	metadata := store.Keys[keyType]
+
+	separatorLen := len(path) + 195
+	nameLen := len(path) + 75
+	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
+	fmt.Printf("   %-*s %-25s %-42s %-10s %-9s %s\n", nameLen, "Name", "Created", "Address", "Role", "Source", "Labels")
+	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
+
+	for _, key := range keys {
+		createdTime := key.Created.Format("02-01-2006 15:04:05")
+
+		address, role, source, labels := "-", "-", "-", ""
+		if keyMetadata, ok := metadata[key.Name]; ok {
+			address = keyMetadata.Address.Hex()
+			source = keyMetadata.Source
+			labels = strings.Join(keyMetadata.Labels, ",")
+			if keyMetadata.Role != "" {
+				role = keyMetadata.Role
+			}
+		}
+
+		fmt.Printf("   %-*s %-25s %-42s %-10s %-9s %s\n", nameLen, key.Path, createdTime, address, role, source, labels)
+	}
+
+	fmt.Printf("   " + strings.Repeat("-", separatorLen) + "\n")
+	return nil
+}
+
+func isSkippedFile(fileName string, skipFiles []string) bool {
+	for _, skipFile := range skipFiles {
+		if fileName == skipFile {
+			return true
+		}
+	}
+	return false
+}
+
+func HasKey(keyStore KeyStore, keyName string) (bool, error) {
+	keyNames, err := keyStore.KeyNames()
+	if err != nil {
+		return false, err
+	}
+	for _, name := range keyNames {
+		if name == keyName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AllowKeyStoreOverwrite asks before a key of the keystore is replaced
+func AllowKeyStoreOverwrite(keyStore KeyStore, keyName string) (bool, error) {
+	exists, err := HasKey(keyStore, keyName)
+	if err != nil || !exists {
+		return err == nil, err
+	}
+	return ConfirmKeyOverwrite(), nil
+}
+
+func keyNameFromPath(keyPath string) string {
+	if !m_isFullPath {
+		return keyPath
+	}
+	_, keyName := filepath.Split(keyPath)
+	return keyName
+}
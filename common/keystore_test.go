@@ -0,0 +1,48 @@
+package wc_common
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestKeyStoresPerCommand(t *testing.T) {
+	password := []byte("correct horse")
+	passwords := &Passwords{password: passwordSource{set: true, password: password}}
+	keyStores := NewKeyStores(passwords)
+	ctx := WithKeyStores(context.Background(), keyStores)
+
+	first, err := GetKeyStore(ctx, KeyTypeKeyVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := GetKeyStore(ctx, KeyTypeKeyVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("a command got two keystores of the same key type")
+	}
+
+	// another command gets its own keystore
+	other, err := GetKeyStore(WithKeyStores(context.Background(), NewKeyStores(nil)), KeyTypeKeyVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first {
+		t.Error("two commands share a keystore")
+	}
+
+	got, err := GetPasswordFromPrompt(ctx, true, "test")
+	if err != nil || !bytes.Equal(got, []byte("correct horse")) {
+		t.Errorf("GetPasswordFromPrompt = %q, %v", got, err)
+	}
+
+	keyStores.Lock()
+	if !bytes.Equal(password, make([]byte, len(password))) {
+		t.Error("Lock did not wipe the password")
+	}
+	if third, _ := GetKeyStore(ctx, KeyTypeKeyVault); third == first {
+		t.Error("a locked keystore is handed out again")
+	}
+}
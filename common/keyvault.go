@@ -0,0 +1,371 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var m_keyVaultDir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, KeyVaultDirName)
+
+type keyVaultEntry struct {
+	PrivateKey SecretHex `json:"private_key"`
+	Created    time.Time `json:"created"`
+}
+
+// KeyVaultKeyStore keeps all the keys in a single scrypt/AES-GCM encrypted
+// file. The keys are only ever decrypted in memory, so it needs neither
+// FUSE nor any external binary. The password and the decrypted keys are
+// kept until Lock
+type KeyVaultKeyStore struct {
+	passwords *Passwords
+	kdf       EnvelopeKDF
+	password  []byte
+	entries   map[string]keyVaultEntry
+}
+
+func init() {
+	RegisterKeyStore(KeyTypeKeyVault, func(options KeyStoreOptions) KeyStore {
+		return &KeyVaultKeyStore{passwords: options.Passwords}
+	})
+}
+
+func (ks *KeyVaultKeyStore) Init(insecure bool) error {
+	if _, err := os.Stat(GetKeyVaultFile()); err == nil {
+		return fmt.Errorf("%s: %w", GetKeyVaultFile(), ErrKeyVaultExists)
+	}
+
+	if err := EnsureDirectory(m_keyVaultDir); err != nil {
+		return err
+	}
+
+	kdf, err := NewEnvelopeKDF()
+	if err != nil {
+		return err
+	}
+
+	password, err := ks.passwords.Password(insecure, "init")
+	if err != nil {
+		return err
+	}
+
+	ks.kdf = kdf
+	ks.password = password
+	ks.entries = map[string]keyVaultEntry{}
+	if err := ks.save(); err != nil {
+		return err
+	}
+
+	fmt.Println("Init keystore done")
+	return nil
+}
+
+func (ks *KeyVaultKeyStore) Create(keyName string, insecure bool) (string, error) {
+	if err := ks.open(); err != nil {
+		return "", err
+	}
+
+	privateKey, err := GenerateRandomKey()
+	if err != nil {
+		return "", err
+	}
+	defer WipePrivateKey(privateKey)
+
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if !ks.allowOverwrite(keyName) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privateKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeKeyVault, keyName, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key: %s\n", keyName)
+	return keyName, nil
+}
+
+func (ks *KeyVaultKeyStore) Import(keyName string, insecure bool) (string, error) {
+	if err := ks.open(); err != nil {
+		return "", err
+	}
+
+	hexKey, err := GetPrivateKeyFromUser()
+	if err != nil {
+		return "", err
+	}
+	defer WipeBytes(hexKey)
+
+	privKey, err := ParsePrivateKey(hexKey)
+	if err != nil {
+		return "", fmt.Errorf("Error converting hex string to ECDSA private key: %w", err)
+	}
+	defer WipePrivateKey(privKey)
+
+	address := GetPublicAddressFromPrivateKey(privKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if !ks.allowOverwrite(keyName) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeKeyVault, keyName, address, KeySourceImported); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Imported key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+func (ks *KeyVaultKeyStore) Export(keyName string) error {
+	privateKey, err := ks.Load(keyName)
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(privateKey)
+
+	fmt.Println("Public key : ", GetPublicAddressFromPrivateKey(privateKey))
+	fmt.Println("Private key : ", hex.EncodeToString(crypto.FromECDSA(privateKey)))
+	return nil
+}
+
+func (ks *KeyVaultKeyStore) Delete(keyName string) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	if _, ok := ks.entries[keyName]; !ok {
+		return fmt.Errorf("Error deleting key %s: %w", keyName, ErrKeyNotFound)
+	}
+
+	WipeBytes(ks.entries[keyName].PrivateKey)
+	delete(ks.entries, keyName)
+	return ks.save()
+}
+
+func (ks *KeyVaultKeyStore) List() error {
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		return err
+	}
+
+	path, _ := filepath.Abs(GetKeyVaultFile())
+
+	var keys []KeyListEntry
+	for _, keyName := range keyNames {
+		keys = append(keys, KeyListEntry{Name: keyName, Path: ks.KeyPath(keyName), Created: ks.entries[keyName].Created})
+	}
+
+	return PrintKeyList(KeyTypeKeyVault, path, keys)
+}
+
+func (ks *KeyVaultKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	keyName := keyNameFromPath(keyPath)
+	entry, ok := ks.entries[keyName]
+	if !ok {
+		return nil, fmt.Errorf("Error reading key %s from %s: %w", keyName, GetKeyVaultFile(), ErrKeyNotFound)
+	}
+
+	privateKey, err := crypto.ToECDSA(entry.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key %s from %s: %w", keyName, GetKeyVaultFile(), err)
+	}
+	return privateKey, nil
+}
+
+func (ks *KeyVaultKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	WipeBytes(ks.entries[keyName].PrivateKey)
+	ks.entries[keyName] = keyVaultEntry{PrivateKey: crypto.FromECDSA(privateKey), Created: time.Now()}
+	return ks.save()
+}
+
+func (ks *KeyVaultKeyStore) KeyNames() ([]string, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	keyNames := make([]string, 0, len(ks.entries))
+	for keyName := range ks.entries {
+		keyNames = append(keyNames, keyName)
+	}
+	sort.Strings(keyNames)
+	return keyNames, nil
+}
+
+// ChangePassword always re-encrypts the whole vault, as all the keys share
+// the vault password
+func (ks *KeyVaultKeyStore) ChangePassword(keyName string, insecure bool) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	if keyName != "" {
+		fmt.Println("All keys of the key vault share one password, changing it for the whole vault")
+	}
+
+	password, err := ks.passwords.NewPassword(insecure, "encrypt key vault")
+	if err != nil {
+		return err
+	}
+
+	kdf, err := NewEnvelopeKDF()
+	if err != nil {
+		WipeBytes(password)
+		return err
+	}
+
+	WipeBytes(ks.password)
+	ks.password = password
+	ks.kdf = kdf
+	if err := ks.save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Changed password of key vault: %s\n", GetKeyVaultFile())
+	return nil
+}
+
+func (ks *KeyVaultKeyStore) UseKeyPath(keyPath string) {
+	dir, file := filepath.Split(keyPath)
+	if file != keyPath {
+		// keys are referenced as <vault dir>/<key name>, the vault file
+		// itself is always KeyVaultFileName inside that directory
+		m_keyVaultDir = filepath.Clean(dir)
+		m_isFullPath = true
+	}
+	fmt.Printf("Using the key path : %s\n", m_keyVaultDir)
+}
+
+func (ks *KeyVaultKeyStore) KeyPath(keyName string) string {
+	return filepath.Join(m_keyVaultDir, keyName)
+}
+
+func (ks *KeyVaultKeyStore) Lock() {
+	WipeBytes(ks.password)
+	for _, entry := range ks.entries {
+		WipeBytes(entry.PrivateKey)
+	}
+	ks.password = nil
+	ks.entries = nil
+}
+
+func GetKeyVaultFile() string {
+	return filepath.Join(m_keyVaultDir, KeyVaultFileName)
+}
+
+// open decrypts the vault into memory, the password is asked only once
+// per command
+func (ks *KeyVaultKeyStore) open() error {
+	if ks.entries != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(GetKeyVaultFile())
+	if os.IsNotExist(err) {
+		return fmt.Errorf("Error reading key vault, run keys init --key-type %s: %w", KeyTypeKeyVault, ErrKeyStoreNotInitialized)
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading key vault: %w", err)
+	}
+
+	var vault EncryptedEnvelope
+	if err := json.Unmarshal(data, &vault); err != nil {
+		return fmt.Errorf("Error parsing key vault %s: %w", GetKeyVaultFile(), err)
+	}
+
+	if vault.Version != KeyVaultVersion {
+		return fmt.Errorf("unsupported version %d: %w", vault.Version, ErrInvalidKeyVault)
+	}
+
+	password, err := ks.passwords.Password(true, "unlock key vault")
+	if err != nil {
+		return err
+	}
+
+	plainText, err := OpenEnvelope(vault, password)
+	if err != nil {
+		WipeBytes(password)
+		if errors.Is(err, ErrInvalidEnvelope) {
+			return fmt.Errorf("Error decrypting key vault %s: %w: %v", GetKeyVaultFile(), ErrInvalidKeyVault, err)
+		}
+		return fmt.Errorf("Error decrypting key vault: %w", err)
+	}
+	defer WipeBytes(plainText)
+
+	var entries map[string]keyVaultEntry
+	if err := json.Unmarshal(plainText, &entries); err != nil {
+		WipeBytes(password)
+		return fmt.Errorf("Error parsing decrypted key vault: %w", err)
+	}
+
+	ks.kdf = vault.KDF
+	ks.password = password
+	ks.entries = entries
+	return nil
+}
+
+// save encrypts the vault with a fresh nonce and atomically replaces the
+// file on disk
+func (ks *KeyVaultKeyStore) save() error {
+	plainText, err := json.Marshal(ks.entries)
+	if err != nil {
+		return fmt.Errorf("Error encoding key vault: %w", err)
+	}
+	defer WipeBytes(plainText)
+
+	vault, err := SealEnvelope(KeyVaultVersion, ks.kdf, ks.password, plainText)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(vault, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding key vault: %w", err)
+	}
+
+	return WriteFileAtomic(GetKeyVaultFile(), data, 0600)
+}
+
+func (ks *KeyVaultKeyStore) allowOverwrite(keyName string) bool {
+	if _, ok := ks.entries[keyName]; ok {
+		return ConfirmKeyOverwrite()
+	}
+	return true
+}
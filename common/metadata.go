@@ -0,0 +1,251 @@
+package wc_common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+var m_keyMetadataFile string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, KeyMetadataFileName)
+
+// KeyMetadata describes a stored key without holding any secret, so it is
+// kept in a plaintext sidecar file next to the keystores
+type KeyMetadata struct {
+	Address common.Address `json:"address"`
+	Role    string         `json:"role,omitempty"`
+	Labels  []string       `json:"labels,omitempty"`
+	Source  string         `json:"source"`
+	Created time.Time      `json:"created"`
+}
+
+// keyMetadataStore maps key type and key name to the metadata of the key
+type keyMetadataStore struct {
+	Version int                               `json:"version"`
+	Keys    map[string]map[string]KeyMetadata `json:"keys"`
+}
+
+func LabelCmd() *cli.Command {
+	var labelCmd = &cli.Command{
+		Name:      "label",
+		Usage:     "set the role and labels of a key in local keystore",
+		UsageText: "label --key-name <keyName> [--role operator|watchtower] [--label <label>] [--remove-label <label>]",
+		Flags: []cli.Flag{
+			&KeyNameFlag,
+			&KeyStoreType,
+			&RoleFlag,
+			&LabelFlag,
+			&RemoveLabelFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.String("key-name") == "" {
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
+			}
+			return LabelKeyCmd(cCtx)
+		},
+	}
+	return labelCmd
+}
+
+func LabelKeyCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	_, ok, err := GetKeyMetadata(keyType, keyName)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// keys stored before metadata was recorded, the address is read
+		// from the key itself
+		if err := recordUnknownKeyMetadata(cCtx.Context, keyType, keyName); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := UpdateKeyMetadata(keyType, keyName, cCtx.String("role"), cCtx.StringSlice("label"), cCtx.StringSlice("remove-label"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Labelled key: %s %s role=%s labels=%s\n", keyName, metadata.Address.Hex(), metadata.Role, strings.Join(metadata.Labels, ","))
+	return nil
+}
+
+func recordUnknownKeyMetadata(ctx context.Context, keyType string, keyName string) error {
+	keyStore, err := GetKeyStore(ctx, keyType)
+	if err != nil {
+		return err
+	}
+
+	exists, err := HasKey(keyStore, keyName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("Error labelling key %s: %w", keyName, ErrKeyNotFound)
+	}
+
+	privateKey, err := keyStore.Load(keyName)
+	if err != nil {
+		return err
+	}
+	address := GetPublicAddressFromPrivateKey(privateKey)
+	WipePrivateKey(privateKey)
+
+	return RecordKeyMetadata(keyType, keyName, address, KeySourceUnknown)
+}
+
+// RecordKeyMetadata stores the metadata of a key that was just written. A
+// key replaced by one with the same address keeps its role and labels
+func RecordKeyMetadata(keyType string, keyName string, address common.Address, source string) error {
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return err
+	}
+
+	metadata, ok := store.Keys[keyType][keyName]
+	if !ok || metadata.Address != address {
+		metadata = KeyMetadata{Address: address, Source: source, Created: time.Now().UTC()}
+	}
+
+	if store.Keys[keyType] == nil {
+		store.Keys[keyType] = map[string]KeyMetadata{}
+	}
+	store.Keys[keyType][keyName] = metadata
+
+	return saveKeyMetadata(store)
+}
+
+// UpdateKeyMetadata sets the role when not empty and adds and removes
+// labels of a key that already has metadata
+func UpdateKeyMetadata(keyType string, keyName string, role string, addLabels []string, removeLabels []string) (KeyMetadata, error) {
+	switch role {
+	case "", KeyRoleOperator, KeyRoleWatchtower:
+	default:
+		return KeyMetadata{}, fmt.Errorf("%s: %w", role, ErrInvalidKeyRole)
+	}
+
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+
+	metadata, ok := store.Keys[keyType][keyName]
+	if !ok {
+		return KeyMetadata{}, fmt.Errorf("Error reading metadata of key %s: %w", keyName, ErrKeyNotFound)
+	}
+
+	if role != "" {
+		metadata.Role = role
+	}
+
+	labels := map[string]bool{}
+	for _, label := range metadata.Labels {
+		labels[label] = true
+	}
+	for _, label := range addLabels {
+		labels[strings.TrimSpace(label)] = true
+	}
+	for _, label := range removeLabels {
+		delete(labels, strings.TrimSpace(label))
+	}
+	delete(labels, "")
+
+	metadata.Labels = metadata.Labels[:0]
+	for label := range labels {
+		metadata.Labels = append(metadata.Labels, label)
+	}
+	sort.Strings(metadata.Labels)
+
+	store.Keys[keyType][keyName] = metadata
+	return metadata, saveKeyMetadata(store)
+}
+
+func GetKeyMetadata(keyType string, keyName string) (KeyMetadata, bool, error) {
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return KeyMetadata{}, false, err
+	}
+	metadata, ok := store.Keys[keyType][keyName]
+	return metadata, ok, nil
+}
+
+// CopyKeyMetadata records the metadata of a key under a new key type or
+// name, keeping role and labels
+func CopyKeyMetadata(fromKeyType string, fromKeyName string, toKeyType string, toKeyName string) error {
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return err
+	}
+
+	metadata, ok := store.Keys[fromKeyType][fromKeyName]
+	if !ok {
+		return nil
+	}
+
+	if store.Keys[toKeyType] == nil {
+		store.Keys[toKeyType] = map[string]KeyMetadata{}
+	}
+	store.Keys[toKeyType][toKeyName] = metadata
+
+	return saveKeyMetadata(store)
+}
+
+func DeleteKeyMetadata(keyType string, keyName string) error {
+	store, err := loadKeyMetadata()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := store.Keys[keyType][keyName]; !ok {
+		return nil
+	}
+	delete(store.Keys[keyType], keyName)
+
+	return saveKeyMetadata(store)
+}
+
+func loadKeyMetadata() (keyMetadataStore, error) {
+	store := keyMetadataStore{Version: KeyMetadataVersion, Keys: map[string]map[string]KeyMetadata{}}
+
+	data, err := os.ReadFile(m_keyMetadataFile)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("Error reading key metadata: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &store); err != nil {
+		return store, fmt.Errorf("Error parsing key metadata %s: %w", m_keyMetadataFile, err)
+	}
+
+	if store.Keys == nil {
+		store.Keys = map[string]map[string]KeyMetadata{}
+	}
+	return store, nil
+}
+
+func saveKeyMetadata(store keyMetadataStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding key metadata: %w", err)
+	}
+
+	if err := EnsureDirectory(filepath.Dir(m_keyMetadataFile)); err != nil {
+		return err
+	}
+	return WriteFileAtomic(m_keyMetadataFile, data, 0600)
+}
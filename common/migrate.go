@@ -0,0 +1,132 @@
+package wc_common
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+func MigrateCmd() *cli.Command {
+	var migrateCmd = &cli.Command{
+		Name:      "migrate",
+		Usage:     "move keys between local keystore types without revealing them",
+		UsageText: "migrate --from <keyType> --to <keyType> [--key-name <keyName> | --all]",
+		Flags: []cli.Flag{
+			&FromKeyTypeFlag,
+			&ToKeyTypeFlag,
+			&KeyNameFlag,
+			&AllKeysFlag,
+			&DeleteSourceFlag,
+			&InsecureFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			return MigrateKeysCmd(cCtx)
+		},
+	}
+	return migrateCmd
+}
+
+func MigrateKeysCmd(cCtx *cli.Context) error {
+	fromKeyType := cCtx.String("from")
+	toKeyType := cCtx.String("to")
+	keyName := cCtx.String("key-name")
+	all := cCtx.Bool("all")
+	deleteSource := cCtx.Bool("delete-source")
+	insecure := cCtx.Bool("insecure")
+
+	if fromKeyType == toKeyType {
+		return fmt.Errorf("%s: %w", fromKeyType, ErrSameKeyType)
+	}
+
+	if all == (keyName != "") {
+		return fmt.Errorf("Error selecting keys to migrate: %w", ErrKeySelection)
+	}
+
+	source, err := GetKeyStore(cCtx.Context, fromKeyType)
+	if err != nil {
+		return err
+	}
+	target, err := GetKeyStore(cCtx.Context, toKeyType)
+	if err != nil {
+		return err
+	}
+
+	keyNames := []string{keyName}
+	if all {
+		if keyNames, err = source.KeyNames(); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range keyNames {
+		if err := migrateKey(source, target, fromKeyType, toKeyType, name, deleteSource, insecure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateKey(source KeyStore, target KeyStore, fromKeyType string, toKeyType string, name string, deleteSource bool, insecure bool) error {
+	if err := ValidateKeyName(name); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	exists, err := HasKey(target, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		fmt.Printf("%s: ", name)
+		if !ConfirmKeyOverwrite() {
+			return nil
+		}
+	}
+
+	privateKey, err := source.Load(name)
+	if err != nil {
+		return err
+	}
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	err = target.Save(name, privateKey, insecure)
+	WipePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	// read the key back from the target before the source can go away, a
+	// target that signs in place only gives its public key
+	migratedKey, err := LoadPublicKey(target, name)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*migratedKey) != address {
+		return fmt.Errorf("Error verifying migrated key %s: %w", name, ErrAddressMismatch)
+	}
+
+	_, ok, err := GetKeyMetadata(fromKeyType, name)
+	if err != nil {
+		return err
+	}
+	if ok {
+		err = CopyKeyMetadata(fromKeyType, name, toKeyType, name)
+	} else {
+		err = RecordKeyMetadata(toKeyType, name, address, KeySourceMigrated)
+	}
+	if err != nil {
+		return err
+	}
+
+	if deleteSource {
+		if err := source.Delete(name); err != nil {
+			return err
+		}
+		if err := DeleteKeyMetadata(fromKeyType, name); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Migrated key: %s %s (%s -> %s)\n", name, address.Hex(), fromKeyType, toKeyType)
+	return nil
+}
@@ -0,0 +1,171 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// memoryKeyStore keeps keys in a map, for the keystore helpers
+type memoryKeyStore struct {
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func newMemoryKeyStore() *memoryKeyStore {
+	return &memoryKeyStore{keys: map[string]*ecdsa.PrivateKey{}}
+}
+
+func (ks *memoryKeyStore) Init(insecure bool) error { return nil }
+func (ks *memoryKeyStore) Create(keyName string, insecure bool) (string, error) {
+	return "", ErrNotSupported
+}
+func (ks *memoryKeyStore) Import(keyName string, insecure bool) (string, error) {
+	return "", ErrNotSupported
+}
+func (ks *memoryKeyStore) Export(keyName string) error { return ErrNotSupported }
+func (ks *memoryKeyStore) List() error                 { return nil }
+func (ks *memoryKeyStore) UseKeyPath(keyPath string)   {}
+func (ks *memoryKeyStore) KeyPath(keyName string) string {
+	return keyName
+}
+func (ks *memoryKeyStore) Lock() {}
+
+func (ks *memoryKeyStore) Delete(keyName string) error {
+	delete(ks.keys, keyName)
+	return nil
+}
+
+func (ks *memoryKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	key, ok := ks.keys[keyPath]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	// a copy, as the caller wipes it
+	return crypto.ToECDSA(crypto.FromECDSA(key))
+}
+
+func (ks *memoryKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	key, err := crypto.ToECDSA(crypto.FromECDSA(privateKey))
+	if err != nil {
+		return err
+	}
+	ks.keys[keyName] = key
+	return nil
+}
+
+func (ks *memoryKeyStore) KeyNames() ([]string, error) {
+	var keyNames []string
+	for keyName := range ks.keys {
+		keyNames = append(keyNames, keyName)
+	}
+	sort.Strings(keyNames)
+	return keyNames, nil
+}
+
+func (ks *memoryKeyStore) ChangePassword(keyName string, insecure bool) error {
+	return ErrNotSupported
+}
+
+// tokenKeyStore keeps its keys like a token, they sign but can't be loaded
+type tokenKeyStore struct {
+	memoryKeyStore
+}
+
+func (ks *tokenKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	return nil, ErrKeyNotExtractable
+}
+
+func (ks *tokenKeyStore) PublicKey(keyName string) (*ecdsa.PublicKey, error) {
+	key, ok := ks.keys[keyName]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return &key.PublicKey, nil
+}
+
+func (ks *tokenKeyStore) SignHash(keyName string, hash []byte) ([]byte, error) {
+	key, ok := ks.keys[keyName]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return crypto.Sign(hash, key)
+}
+
+// useTestKeyMetadata keeps the key metadata of the test in a temporary
+// directory
+func useTestKeyMetadata(t *testing.T) {
+	t.Helper()
+	previous := m_keyMetadataFile
+	m_keyMetadataFile = filepath.Join(t.TempDir(), KeyMetadataFileName)
+	t.Cleanup(func() { m_keyMetadataFile = previous })
+}
+
+func TestMigrateToKeySigner(t *testing.T) {
+	useTestKeyMetadata(t)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newMemoryKeyStore()
+	if err := source.Save("watchtower1", key, true); err != nil {
+		t.Fatal(err)
+	}
+	target := &tokenKeyStore{memoryKeyStore: *newMemoryKeyStore()}
+
+	if err := migrateKey(source, target, KeyTypeKeyVault, KeyTypePKCS11, "watchtower1", true, true); err != nil {
+		t.Fatalf("migrateKey to a keystore that signs in place: %v", err)
+	}
+
+	publicKey, err := target.PublicKey("watchtower1")
+	if err != nil || crypto.PubkeyToAddress(*publicKey) != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("migrated key is not the source key: %v", err)
+	}
+	if exists, _ := HasKey(source, "watchtower1"); exists {
+		t.Error("source key was not deleted")
+	}
+	metadata, ok, err := GetKeyMetadata(KeyTypePKCS11, "watchtower1")
+	if err != nil || !ok || metadata.Address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("metadata of the migrated key = %+v, %v, %v", metadata, ok, err)
+	}
+}
+
+// wrongKeyStore stores another key than the one it is given
+type wrongKeyStore struct {
+	tokenKeyStore
+}
+
+func (ks *wrongKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	ks.keys[keyName] = key
+	return nil
+}
+
+func TestMigrateVerifiesTarget(t *testing.T) {
+	useTestKeyMetadata(t)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newMemoryKeyStore()
+	if err := source.Save("watchtower1", key, true); err != nil {
+		t.Fatal(err)
+	}
+	target := &wrongKeyStore{tokenKeyStore{memoryKeyStore: *newMemoryKeyStore()}}
+
+	err = migrateKey(source, target, KeyTypeKeyVault, KeyTypePKCS11, "watchtower1", true, true)
+	if !errors.Is(err, ErrAddressMismatch) {
+		t.Errorf("migrateKey error = %v, want ErrAddressMismatch", err)
+	}
+	if exists, _ := HasKey(source, "watchtower1"); !exists {
+		t.Error("source key was deleted after a failed migration")
+	}
+}
@@ -0,0 +1,221 @@
+package wc_common
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// networks.json holds the networks witnesschain is deployed on, the user
+// networks file adds networks and overrides them by chain id
+//
+//go:embed networks.json
+var m_builtInNetworks []byte
+
+var m_networksFile string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, NetworksFileName)
+
+type networksFile struct {
+	Version  int           `json:"version"`
+	Networks []ChainConfig `json:"networks"`
+}
+
+// Network is a known network with where it is defined
+type Network struct {
+	ChainConfig
+	Source string
+}
+
+func GetNetworksFile() string {
+	return m_networksFile
+}
+
+// GetNetworks returns the built-in networks and the networks of the user
+// networks file, keyed by chain id
+func GetNetworks() (map[string]ChainConfig, error) {
+	networks, err := ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	chainConfigs := map[string]ChainConfig{}
+	for _, network := range networks {
+		chainConfigs[network.ChainID.String()] = network.ChainConfig
+	}
+	return chainConfigs, nil
+}
+
+// ListNetworks returns every known network sorted by chain id. A user
+// network with the chain id of a built-in network overrides it
+func ListNetworks() ([]Network, error) {
+	var builtIn networksFile
+	if err := json.Unmarshal(m_builtInNetworks, &builtIn); err != nil {
+		return nil, fmt.Errorf("Error parsing built-in networks: %w", err)
+	}
+
+	user, err := loadUserNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	byChainID := map[string]Network{}
+	for _, chainConfig := range builtIn.Networks {
+		byChainID[chainConfig.ChainID.String()] = Network{ChainConfig: chainConfig, Source: NetworkSourceBuiltIn}
+	}
+	for _, chainConfig := range user.Networks {
+		source := NetworkSourceUser
+		if _, ok := byChainID[chainConfig.ChainID.String()]; ok {
+			source = NetworkSourceOverride
+		}
+		byChainID[chainConfig.ChainID.String()] = Network{ChainConfig: chainConfig, Source: source}
+	}
+
+	networks := make([]Network, 0, len(byChainID))
+	for _, network := range byChainID {
+		networks = append(networks, network)
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].ChainID.Cmp(&networks[j].ChainID) < 0
+	})
+	return networks, nil
+}
+
+// FindNetwork returns the network with the name or the chain id nameOrID
+func FindNetwork(nameOrID string) (Network, error) {
+	networks, err := ListNetworks()
+	if err != nil {
+		return Network{}, err
+	}
+
+	for _, network := range networks {
+		if network.Name == nameOrID || network.ChainID.String() == nameOrID {
+			return network, nil
+		}
+	}
+	return Network{}, fmt.Errorf("network %s: %w", nameOrID, ErrUnknownNetwork)
+}
+
+// AddNetwork writes chainConfig to the user networks file, replacing the
+// user network with the same chain id
+func AddNetwork(chainConfig ChainConfig) error {
+	if err := ValidateNetwork(chainConfig); err != nil {
+		return err
+	}
+
+	networks, err := ListNetworks()
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		if network.Name == chainConfig.Name && network.ChainID.Cmp(&chainConfig.ChainID) != 0 {
+			return fmt.Errorf("network name %s is used by chain id %s: %w", chainConfig.Name, network.ChainID.String(), ErrNetworkExists)
+		}
+	}
+
+	user, err := loadUserNetworks()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range user.Networks {
+		if user.Networks[i].ChainID.Cmp(&chainConfig.ChainID) == 0 {
+			user.Networks[i] = chainConfig
+			replaced = true
+		}
+	}
+	if !replaced {
+		user.Networks = append(user.Networks, chainConfig)
+	}
+
+	return saveUserNetworks(user)
+}
+
+// RemoveNetwork removes a network of the user networks file. A removed
+// override brings the built-in network back, built-in networks can't be
+// removed
+func RemoveNetwork(nameOrID string) (Network, error) {
+	network, err := FindNetwork(nameOrID)
+	if err != nil {
+		return Network{}, err
+	}
+	if network.Source == NetworkSourceBuiltIn {
+		return Network{}, fmt.Errorf("network %s: %w", network.Name, ErrBuiltInNetwork)
+	}
+
+	user, err := loadUserNetworks()
+	if err != nil {
+		return Network{}, err
+	}
+
+	networks := []ChainConfig{}
+	for _, chainConfig := range user.Networks {
+		if chainConfig.ChainID.Cmp(&network.ChainID) != 0 {
+			networks = append(networks, chainConfig)
+		}
+	}
+	user.Networks = networks
+
+	return network, saveUserNetworks(user)
+}
+
+// ValidateNetwork checks that a network has a name, a chain id and an
+// operator registry, which every command uses
+func ValidateNetwork(chainConfig ChainConfig) error {
+	if chainConfig.Name == "" {
+		return fmt.Errorf("network name is empty: %w", ErrInvalidNetwork)
+	}
+	if chainConfig.ChainID.Sign() <= 0 {
+		return fmt.Errorf("network %s has no chain id: %w", chainConfig.Name, ErrInvalidNetwork)
+	}
+	if chainConfig.OperatorRegistryAddress == (common.Address{}) {
+		return fmt.Errorf("network %s has no operator registry: %w", chainConfig.Name, ErrInvalidNetwork)
+	}
+	if (chainConfig.WitnessHubAddress == (common.Address{})) != (chainConfig.AVSDirectoryAddress == (common.Address{})) {
+		return fmt.Errorf("network %s needs both the witness hub and the avs directory, or none: %w", chainConfig.Name, ErrInvalidNetwork)
+	}
+	return nil
+}
+
+func loadUserNetworks() (networksFile, error) {
+	user := networksFile{Version: NetworksVersion}
+
+	data, err := os.ReadFile(m_networksFile)
+	if os.IsNotExist(err) {
+		return user, nil
+	}
+	if err != nil {
+		return user, fmt.Errorf("Error reading networks file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &user); err != nil {
+		return user, fmt.Errorf("Error parsing networks file %s: %w", m_networksFile, err)
+	}
+	for _, chainConfig := range user.Networks {
+		if err := ValidateNetwork(chainConfig); err != nil {
+			return user, fmt.Errorf("Error in networks file %s: %w", m_networksFile, err)
+		}
+	}
+	return user, nil
+}
+
+func saveUserNetworks(user networksFile) error {
+	user.Version = NetworksVersion
+	sort.Slice(user.Networks, func(i, j int) bool {
+		return user.Networks[i].ChainID.Cmp(&user.Networks[j].ChainID) < 0
+	})
+
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding networks file: %w", err)
+	}
+
+	if err := EnsureDirectory(filepath.Dir(m_networksFile)); err != nil {
+		return err
+	}
+	return WriteFileAtomic(m_networksFile, append(data, '\n'), 0644)
+}
@@ -1,20 +1,248 @@
 package wc_common
 
 import (
-	"strings"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
 
 	"github.com/howeyc/gopass"
+	"github.com/urfave/cli/v2"
 	passwordvalidator "github.com/wagslane/go-password-validator"
 )
 
-func ValidatePassword(password string) {
-	err := passwordvalidator.Validate(password, MinEntropyBits)
-	CheckError(err, "Password does not meet the required security standards"+
-		"\nIf you want to create keys for testing with weak/no password, use --insecure flag. Do NOT use those keys in production")
+const KEYSTOREPASSWORD = "KEYSTORE_PASSWORD"
+
+// passwordSource holds a password given through --password-file,
+// --password-fd or the environment
+type passwordSource struct {
+	set      bool
+	password []byte
+}
+
+// Passwords holds the password sources of one command run, they are used
+// instead of every prompt of the command so keys can be used from scripts
+// and services. A nil Passwords always prompts
+type Passwords struct {
+	insecure    bool
+	password    passwordSource
+	newPassword passwordSource
+}
+
+func ValidatePassword(password []byte) error {
+	if err := passwordvalidator.Validate(string(password), MinEntropyBits); err != nil {
+		return fmt.Errorf("Password does not meet the required security standards: %w"+
+			"\nIf you want to create keys for testing with weak/no password, use --insecure flag. Do NOT use those keys in production", err)
+	}
+	return nil
+}
+
+// ReadHiddenInput reads a secret from the terminal, the caller wipes it
+// once used
+func ReadHiddenInput() ([]byte, error) {
+	input, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading input: %w", err)
+	}
+	return bytes.TrimSpace(input), nil
+}
+
+// AddPasswordFlags adds the non-interactive password flags to every command
+// of the tree that has an action. The passwords and the keystores of the
+// command are kept in its context before the action runs, and are locked
+// and wiped once it returns
+func AddPasswordFlags(cmds []*cli.Command) {
+	for _, cmd := range cmds {
+		AddPasswordFlags(cmd.Subcommands)
+		if cmd.Action == nil {
+			continue
+		}
+
+		if !hasFlag(cmd, InsecureFlag.Name) {
+			cmd.Flags = append(cmd.Flags, &InsecureFlag)
+		}
+		cmd.Flags = append(cmd.Flags, &PasswordFileFlag, &PasswordFdFlag)
+
+		before := cmd.Before
+		cmd.Before = func(cCtx *cli.Context) error {
+			passwords, err := NewPasswords(cCtx)
+			if err != nil {
+				return err
+			}
+			cCtx.Context = WithKeyStores(cCtx.Context, NewKeyStores(passwords))
+			if before != nil {
+				return before(cCtx)
+			}
+			return nil
+		}
+
+		// After runs even when Before or the action fails
+		after := cmd.After
+		cmd.After = func(cCtx *cli.Context) error {
+			if keyStores, ok := cCtx.Context.Value(keyStoresContextKey{}).(*KeyStores); ok {
+				keyStores.Lock()
+			}
+			if after != nil {
+				return after(cCtx)
+			}
+			return nil
+		}
+	}
+}
+
+// NewPasswords reads the password from --password-file, --password-fd or
+// the KEYSTORE_PASSWORD (or W3SECRETPASSPHRASE) environment variable, in
+// that order. The new password of passwd is read from --new-password-file
+func NewPasswords(cCtx *cli.Context) (*Passwords, error) {
+	passwords := &Passwords{insecure: cCtx.Bool(InsecureFlag.Name)}
+
+	var err error
+	switch {
+	case cCtx.String(PasswordFileFlag.Name) != "":
+		passwords.password, err = readPasswordFile(cCtx.String(PasswordFileFlag.Name))
+	case cCtx.IsSet(PasswordFdFlag.Name):
+		file := os.NewFile(uintptr(cCtx.Int(PasswordFdFlag.Name)), "password-fd")
+		if file == nil {
+			return nil, fmt.Errorf("Error reading password from fd: %w", os.ErrInvalid)
+		}
+		passwords.password, err = readPasswordLine(file)
+		file.Close()
+	default:
+		for _, env := range []string{KEYSTOREPASSWORD, W3SECRETPASSPHRASE} {
+			if value, ok := os.LookupEnv(env); ok {
+				passwords.password = passwordSource{set: true, password: bytes.TrimSpace([]byte(value))}
+				os.Unsetenv(env)
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if cCtx.String(NewPasswordFileFlag.Name) != "" {
+		if passwords.newPassword, err = readPasswordFile(cCtx.String(NewPasswordFileFlag.Name)); err != nil {
+			passwords.Wipe()
+			return nil, err
+		}
+	}
+	return passwords, nil
+}
+
+// Password returns the password of the password source, or asks for it.
+// The caller wipes it once used
+func (passwords *Passwords) Password(insecure bool, desc string) ([]byte, error) {
+	var source passwordSource
+	if passwords != nil {
+		source = passwords.password
+	}
+	return passwords.fromSourceOrPrompt(source, insecure, desc)
+}
+
+// NewPassword returns the password of --new-password-file, or asks for it
+// twice. The caller wipes it once used
+func (passwords *Passwords) NewPassword(insecure bool, desc string) ([]byte, error) {
+	var source passwordSource
+	if passwords != nil {
+		source = passwords.newPassword
+	}
+	password, err := passwords.fromSourceOrPrompt(source, insecure, desc)
+	if err != nil || source.set {
+		return password, err
+	}
+
+	fmt.Printf("Repeat password to %s: ", desc)
+	repeated, err := ReadHiddenInput()
+	defer WipeBytes(repeated)
+	if err != nil || !bytes.Equal(repeated, password) {
+		WipeBytes(password)
+		if err == nil {
+			err = ErrPasswordMismatch
+		}
+		return nil, fmt.Errorf("Error reading new password: %w", err)
+	}
+
+	return password, nil
+}
+
+// Wipe overwrites the passwords read from the password sources
+func (passwords *Passwords) Wipe() {
+	if passwords == nil {
+		return
+	}
+	passwords.password.wipe()
+	passwords.newPassword.wipe()
+}
+
+func (passwords *Passwords) fromSourceOrPrompt(source passwordSource, insecure bool, desc string) ([]byte, error) {
+	password, ok, err := passwordFromSource(source, passwords != nil && passwords.insecure)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		fmt.Printf("Enter password to %s: ", desc)
+		if password, err = ReadHiddenInput(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !insecure {
+		if err := ValidatePassword(password); err != nil {
+			WipeBytes(password)
+			return nil, err
+		}
+	}
+
+	return password, nil
+}
+
+// passwordFromSource returns a copy of the password of the configured
+// source, an empty password is refused unless --insecure is set
+func passwordFromSource(source passwordSource, insecure bool) ([]byte, bool, error) {
+	if !source.set {
+		return nil, false, nil
+	}
+
+	if len(source.password) == 0 && !insecure {
+		return nil, false, fmt.Errorf("Error reading password: %w", ErrEmptyPassword)
+	}
+	return append([]byte{}, source.password...), true, nil
+}
+
+func (source *passwordSource) wipe() {
+	WipeBytes(source.password)
+	*source = passwordSource{}
+}
+
+func readPasswordFile(path string) (passwordSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return passwordSource{}, fmt.Errorf("Error reading password file: %w", err)
+	}
+	defer file.Close()
+
+	return readPasswordLine(file)
+}
+
+// readPasswordLine reads the first line only, so a writer that keeps the
+// descriptor open does not block the command
+func readPasswordLine(reader io.Reader) (passwordSource, error) {
+	line, err := bufio.NewReader(reader).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return passwordSource{}, fmt.Errorf("Error reading password: %w", err)
+	}
+	return passwordSource{set: true, password: bytes.TrimSpace(line)}, nil
 }
 
-func ReadHiddenInput() string {
-	password, err := gopass.GetPasswdMasked()
-	CheckError(err, "Error reading input")
-	return strings.TrimSpace(string(password))
+func hasFlag(cmd *cli.Command, name string) bool {
+	for _, flag := range cmd.Flags {
+		for _, flagName := range flag.Names() {
+			if flagName == name {
+				return true
+			}
+		}
+	}
+	return false
 }
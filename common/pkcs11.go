@@ -0,0 +1,661 @@
+package wc_common
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+const (
+	PKCS11MODULE     = "PKCS11_MODULE"
+	PKCS11TOKENLABEL = "PKCS11_TOKEN_LABEL"
+)
+
+// pkcs11PendingSuffix labels a new key until it replaces the key of its
+// name. Key names have no whitespace, so it is never the label of a key
+const pkcs11PendingSuffix = " pending"
+
+var m_pkcs11Dir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, PKCS11DirName)
+
+// secp256k1 named curve OID 1.3.132.0.10, DER encoded for CKA_EC_PARAMS
+var m_secp256k1Params = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// PKCS11Config is the token the keystore uses, written by keys init. The
+// PKCS11_MODULE and PKCS11_TOKEN_LABEL environment variables override it
+type PKCS11Config struct {
+	Module     string `json:"module"`
+	TokenLabel string `json:"token_label"`
+}
+
+// PKCS11KeyStore keeps secp256k1 keys inside a PKCS#11 token such as an HSM
+// or SoftHSM. Private keys are generated on the token, are not extractable
+// and never exist as files, so Load and Export fail and the keys are used
+// through SignHash. The PIN is read like a keystore password and the
+// session is kept logged in until Lock
+type PKCS11KeyStore struct {
+	passwords *Passwords
+	ctx       *pkcs11.Ctx
+	session   pkcs11.SessionHandle
+}
+
+func init() {
+	RegisterKeyStore(KeyTypePKCS11, func(options KeyStoreOptions) KeyStore {
+		return &PKCS11KeyStore{passwords: options.Passwords}
+	})
+}
+
+func (ks *PKCS11KeyStore) Init(insecure bool) error {
+	config, err := readPKCS11Config()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	if config.Module == "" {
+		fmt.Printf("Path of the PKCS#11 module: ")
+		line, _ := reader.ReadString('\n')
+		config.Module = strings.TrimSpace(line)
+	}
+	if config.TokenLabel == "" {
+		fmt.Printf("Label of the token: ")
+		line, _ := reader.ReadString('\n')
+		config.TokenLabel = strings.TrimSpace(line)
+	}
+
+	// check the module, the token and the PIN before keeping them
+	if err := ks.login(config); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding pkcs11 config: %w", err)
+	}
+	if err := EnsureDirectory(m_pkcs11Dir); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(GetPKCS11ConfigFile(), data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Println("Init keystore done")
+	return nil
+}
+
+func (ks *PKCS11KeyStore) Create(keyName string, insecure bool) (string, error) {
+	if err := ks.open(); err != nil {
+		return "", err
+	}
+
+	if keyName != "" {
+		if err := ValidateKeyName(keyName); err != nil {
+			return "", fmt.Errorf("Error validating key name: %w", err)
+		}
+		if allow, err := AllowKeyStoreOverwrite(ks, keyName); err != nil || !allow {
+			return "", err
+		}
+	}
+
+	// the key is labelled with its name, or its address when unnamed, once
+	// it is generated
+	label := keyName + pkcs11PendingSuffix
+	if err := ks.destroyObjects(label); err != nil {
+		return "", err
+	}
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, m_secp256k1Params),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)}
+
+	publicHandle, privateHandle, err := ks.ctx.GenerateKeyPair(ks.session, mechanism, public, private)
+	if err != nil {
+		return "", fmt.Errorf("Error generating key on the token: %w", err)
+	}
+
+	publicKey, err := ks.publicKey(publicHandle)
+	if err != nil {
+		return "", err
+	}
+	address := crypto.PubkeyToAddress(*publicKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+	if err := ks.replaceKey(keyName, label, publicHandle, privateHandle); err != nil {
+		return "", err
+	}
+
+	if err := RecordKeyMetadata(KeyTypePKCS11, keyName, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+// Import copies an existing key into the token, where it is marked
+// sensitive and not extractable
+func (ks *PKCS11KeyStore) Import(keyName string, insecure bool) (string, error) {
+	if err := ks.open(); err != nil {
+		return "", err
+	}
+
+	hexKey, err := GetPrivateKeyFromUser()
+	if err != nil {
+		return "", err
+	}
+	defer WipeBytes(hexKey)
+
+	privateKey, err := ParsePrivateKey(hexKey)
+	if err != nil {
+		return "", fmt.Errorf("Error converting hex string to ECDSA private key: %w", err)
+	}
+	defer WipePrivateKey(privateKey)
+
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if allow, err := AllowKeyStoreOverwrite(ks, keyName); err != nil || !allow {
+		return "", err
+	}
+
+	if err := ks.Save(keyName, privateKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypePKCS11, keyName, address, KeySourceImported); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Imported key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+func (ks *PKCS11KeyStore) Export(keyName string) error {
+	return fmt.Errorf("Error exporting key %s: %w", keyName, ErrKeyNotExtractable)
+}
+
+func (ks *PKCS11KeyStore) Delete(keyName string) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	handles, err := ks.findObjects(keyName, pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return err
+	}
+	if len(handles) == 0 {
+		return fmt.Errorf("Error deleting key %s: %w", keyName, ErrKeyNotFound)
+	}
+
+	for _, handle := range handles {
+		if err := ks.ctx.DestroyObject(ks.session, handle); err != nil {
+			return fmt.Errorf("Error deleting key %s: %w", keyName, err)
+		}
+	}
+	return nil
+}
+
+func (ks *PKCS11KeyStore) List() error {
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		return err
+	}
+
+	config, err := readPKCS11Config()
+	if err != nil {
+		return err
+	}
+	path := "pkcs11:token=" + config.TokenLabel
+
+	var keys []KeyListEntry
+	for _, keyName := range keyNames {
+		entry := KeyListEntry{Name: keyName, Path: path + ";object=" + keyName}
+		if metadata, ok, err := GetKeyMetadata(KeyTypePKCS11, keyName); err != nil {
+			return err
+		} else if ok {
+			entry.Created = metadata.Created
+		}
+		keys = append(keys, entry)
+	}
+
+	return PrintKeyList(KeyTypePKCS11, path, keys)
+}
+
+func (ks *PKCS11KeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	return nil, fmt.Errorf("Error loading key %s: %w", keyPath, ErrKeyNotExtractable)
+}
+
+// Save imports privateKey into the token as a key pair labelled keyName.
+// A key with that label is only deleted once the new one is stored
+func (ks *PKCS11KeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	label := keyName + pkcs11PendingSuffix
+	if err := ks.destroyObjects(label); err != nil {
+		return err
+	}
+
+	point, err := asn1.Marshal(crypto.FromECDSAPub(&privateKey.PublicKey))
+	if err != nil {
+		return fmt.Errorf("Error encoding public key: %w", err)
+	}
+	value := crypto.FromECDSA(privateKey)
+	defer WipeBytes(value)
+
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, m_secp256k1Params),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, point),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, m_secp256k1Params),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE, value),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+
+	publicHandle, err := ks.ctx.CreateObject(ks.session, public)
+	if err != nil {
+		return fmt.Errorf("Error storing public key on the token: %w", err)
+	}
+	privateHandle, err := ks.ctx.CreateObject(ks.session, private)
+	if err != nil {
+		ks.ctx.DestroyObject(ks.session, publicHandle)
+		return fmt.Errorf("Error storing private key on the token: %w", err)
+	}
+	return ks.replaceKey(keyName, label, publicHandle, privateHandle)
+}
+
+// KeyNames returns the labels of the secp256k1 private keys of the token
+func (ks *PKCS11KeyStore) KeyNames() ([]string, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	handles, err := ks.findObjects("", pkcs11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyNames []string
+	for _, handle := range handles {
+		attributes, err := ks.ctx.GetAttributeValue(ks.session, handle, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Error reading key on the token: %w", err)
+		}
+		if string(attributes[1].Value) != string(m_secp256k1Params) || strings.HasSuffix(string(attributes[0].Value), pkcs11PendingSuffix) {
+			continue
+		}
+		keyNames = append(keyNames, string(attributes[0].Value))
+	}
+	sort.Strings(keyNames)
+	return keyNames, nil
+}
+
+// ChangePassword changes the user PIN of the token, which protects every
+// key, so keyName is ignored
+func (ks *PKCS11KeyStore) ChangePassword(keyName string, insecure bool) error {
+	config, err := readPKCS11Config()
+	if err != nil {
+		return err
+	}
+
+	oldPin, err := ks.passwords.Password(true, "unlock the pkcs11 token")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(oldPin)
+
+	if err := ks.loginWithPin(config, oldPin); err != nil {
+		return err
+	}
+
+	newPin, err := ks.passwords.NewPassword(insecure, "the pkcs11 token")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(newPin)
+
+	if err := ks.ctx.SetPIN(ks.session, string(oldPin), string(newPin)); err != nil {
+		return fmt.Errorf("Error changing the PIN of the token: %w", err)
+	}
+
+	fmt.Printf("Changed PIN of token: %s\n", config.TokenLabel)
+	return nil
+}
+
+// UseKeyPath does nothing, keys of the token are referenced by label
+func (ks *PKCS11KeyStore) UseKeyPath(keyPath string) {
+}
+
+func (ks *PKCS11KeyStore) KeyPath(keyName string) string {
+	return keyName
+}
+
+func (ks *PKCS11KeyStore) Lock() {
+	if ks.ctx == nil {
+		return
+	}
+
+	ks.ctx.Logout(ks.session)
+	ks.ctx.CloseSession(ks.session)
+	ks.ctx.Finalize()
+	ks.ctx.Destroy()
+	ks.ctx = nil
+}
+
+// PublicKey reads the public key of keyName from the token
+func (ks *PKCS11KeyStore) PublicKey(keyName string) (*ecdsa.PublicKey, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	handle, err := ks.findObject(keyName, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return nil, err
+	}
+	return ks.publicKey(handle)
+}
+
+// SignHash signs a 32 byte hash inside the token. The signature is
+// [R || S || V] with a low S and V as 0/1, as crypto.Sign returns
+func (ks *PKCS11KeyStore) SignHash(keyName string, hash []byte) ([]byte, error) {
+	publicKey, err := ks.PublicKey(keyName)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := ks.findObject(keyName, pkcs11.CKO_PRIVATE_KEY)
+	if err != nil {
+		return nil, err
+	}
+
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}
+	if err := ks.ctx.SignInit(ks.session, mechanism, handle); err != nil {
+		return nil, fmt.Errorf("Error signing with key %s: %w", keyName, err)
+	}
+	rs, err := ks.ctx.Sign(ks.session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("Error signing with key %s: %w", keyName, err)
+	}
+
+	return RecoverableSignature(hash, rs, publicKey)
+}
+
+// RecoverableSignature turns the [R || S] signature of a token or a remote
+// signer into the [R || S || V] form of crypto.Sign for publicKey
+func RecoverableSignature(hash []byte, rs []byte, publicKey *ecdsa.PublicKey) ([]byte, error) {
+	if len(rs) != 64 {
+		return nil, fmt.Errorf("Error reading signature of %d bytes: %w", len(rs), ErrInvalidSignature)
+	}
+
+	// ethereum only accepts the lower of the two valid S values
+	curveOrder := crypto.S256().Params().N
+	s := new(big.Int).SetBytes(rs[32:])
+	if s.Cmp(new(big.Int).Rsh(curveOrder, 1)) > 0 {
+		s.Sub(curveOrder, s)
+	}
+
+	signature := make([]byte, 65)
+	copy(signature[:32], rs[:32])
+	s.FillBytes(signature[32:64])
+
+	expected := crypto.FromECDSAPub(publicKey)
+	for v := byte(0); v < 2; v++ {
+		signature[64] = v
+		recovered, err := crypto.Ecrecover(hash, signature)
+		if err == nil && string(recovered) == string(expected) {
+			return signature, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+func GetPKCS11ConfigFile() string {
+	return filepath.Join(m_pkcs11Dir, PKCS11ConfigName)
+}
+
+func readPKCS11Config() (PKCS11Config, error) {
+	var config PKCS11Config
+
+	data, err := os.ReadFile(GetPKCS11ConfigFile())
+	if err != nil && !os.IsNotExist(err) {
+		return config, fmt.Errorf("Error reading pkcs11 config: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("Error unmarshaling pkcs11 config: %w", err)
+		}
+	}
+
+	if module, ok := os.LookupEnv(PKCS11MODULE); ok {
+		config.Module = module
+	}
+	if tokenLabel, ok := os.LookupEnv(PKCS11TOKENLABEL); ok {
+		config.TokenLabel = tokenLabel
+	}
+	return config, nil
+}
+
+// open logs in to the token on first use, the PIN is read like a keystore
+// password
+func (ks *PKCS11KeyStore) open() error {
+	if ks.ctx != nil {
+		return nil
+	}
+
+	config, err := readPKCS11Config()
+	if err != nil {
+		return err
+	}
+	if config.Module == "" || config.TokenLabel == "" {
+		return fmt.Errorf("Run keys init -t pkcs11 or set %s and %s: %w: %w", PKCS11MODULE, PKCS11TOKENLABEL, ErrKeyStoreNotInitialized, ErrTokenNotFound)
+	}
+
+	return ks.login(config)
+}
+
+func (ks *PKCS11KeyStore) login(config PKCS11Config) error {
+	pin, err := ks.passwords.Password(true, "unlock the pkcs11 token")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(pin)
+
+	return ks.loginWithPin(config, pin)
+}
+
+func (ks *PKCS11KeyStore) loginWithPin(config PKCS11Config, pin []byte) error {
+	ks.Lock()
+
+	ctx := pkcs11.New(config.Module)
+	if ctx == nil {
+		return fmt.Errorf("Error loading pkcs11 module %s: %w", config.Module, ErrTokenNotFound)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return fmt.Errorf("Error initializing pkcs11 module: %w", err)
+	}
+
+	session, err := openTokenSession(ctx, config.TokenLabel)
+	if err == nil {
+		if err = ctx.Login(session, pkcs11.CKU_USER, string(pin)); err != nil {
+			ctx.CloseSession(session)
+			err = fmt.Errorf("Error logging in to token %s: %w", config.TokenLabel, ErrInvalidPassword)
+		}
+	}
+	if err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return err
+	}
+
+	ks.ctx = ctx
+	ks.session = session
+	return nil
+}
+
+func openTokenSession(ctx *pkcs11.Ctx, tokenLabel string) (pkcs11.SessionHandle, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("Error listing pkcs11 slots: %w", err)
+	}
+
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil || info.Label != tokenLabel {
+			continue
+		}
+
+		session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			return 0, fmt.Errorf("Error opening session on token %s: %w", tokenLabel, err)
+		}
+		return session, nil
+	}
+	return 0, fmt.Errorf("%s: %w", tokenLabel, ErrTokenNotFound)
+}
+
+// findObjects returns the EC keys of the given classes labelled keyName, or
+// all of them when keyName is empty
+func (ks *PKCS11KeyStore) findObjects(keyName string, classes ...uint) ([]pkcs11.ObjectHandle, error) {
+	var handles []pkcs11.ObjectHandle
+	for _, class := range classes {
+		template := []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+		}
+		if keyName != "" {
+			template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyName))
+		}
+
+		if err := ks.ctx.FindObjectsInit(ks.session, template); err != nil {
+			return nil, fmt.Errorf("Error searching keys on the token: %w", err)
+		}
+		for {
+			found, _, err := ks.ctx.FindObjects(ks.session, 100)
+			if err != nil {
+				ks.ctx.FindObjectsFinal(ks.session)
+				return nil, fmt.Errorf("Error searching keys on the token: %w", err)
+			}
+			if len(found) == 0 {
+				break
+			}
+			handles = append(handles, found...)
+		}
+		if err := ks.ctx.FindObjectsFinal(ks.session); err != nil {
+			return nil, fmt.Errorf("Error searching keys on the token: %w", err)
+		}
+	}
+	return handles, nil
+}
+
+func (ks *PKCS11KeyStore) findObject(keyName string, class uint) (pkcs11.ObjectHandle, error) {
+	handles, err := ks.findObjects(keyName, class)
+	if err != nil {
+		return 0, err
+	}
+	if len(handles) == 0 {
+		return 0, fmt.Errorf("%s: %w", keyName, ErrKeyNotFound)
+	}
+	return handles[0], nil
+}
+
+// publicKey reads CKA_EC_POINT, which tokens return either as a DER octet
+// string or as the raw uncompressed point
+func (ks *PKCS11KeyStore) publicKey(handle pkcs11.ObjectHandle) (*ecdsa.PublicKey, error) {
+	attributes, err := ks.ctx.GetAttributeValue(ks.session, handle, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil)})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading public key on the token: %w", err)
+	}
+
+	point := attributes[0].Value
+	if len(point) != 65 {
+		var raw []byte
+		if _, err := asn1.Unmarshal(point, &raw); err != nil {
+			return nil, fmt.Errorf("Error decoding public key: %w", err)
+		}
+		point = raw
+	}
+
+	publicKey, err := crypto.UnmarshalPubkey(point)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding public key: %w", err)
+	}
+	return publicKey, nil
+}
+
+// replaceKey deletes the key labelled keyName, if any, and labels the new
+// key pair stored under label with keyName
+func (ks *PKCS11KeyStore) replaceKey(keyName string, label string, handles ...pkcs11.ObjectHandle) error {
+	if err := ks.destroyObjects(keyName); err != nil {
+		return err
+	}
+
+	labelAttribute := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyName)}
+	for _, handle := range handles {
+		if err := ks.ctx.SetAttributeValue(ks.session, handle, labelAttribute); err != nil {
+			return fmt.Errorf("Error labelling key on the token, it is left as %q: %w", label, err)
+		}
+	}
+	return nil
+}
+
+// destroyObjects deletes the key pair labelled label, a missing key is not
+// an error
+func (ks *PKCS11KeyStore) destroyObjects(label string) error {
+	handles, err := ks.findObjects(label, pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		return err
+	}
+
+	for _, handle := range handles {
+		if err := ks.ctx.DestroyObject(ks.session, handle); err != nil {
+			return fmt.Errorf("Error deleting key %s: %w", label, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/miekg/pkcs11"
+)
+
+// softHSMModuleEnv names the SoftHSM module of the pkcs11 tests, they look in
+// the usual install paths when it is not set
+const softHSMModuleEnv = "SOFTHSM2_MODULE"
+
+var m_softHSMModules = []string{
+	"/usr/lib/softhsm/libsofthsm2.so",
+	"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+	"/usr/lib64/pkcs11/libsofthsm2.so",
+	"/usr/local/lib/softhsm/libsofthsm2.so",
+	"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+}
+
+const (
+	testTokenLabel = "operator-cli-test"
+	testTokenPin   = "1234"
+)
+
+// softHSMModule returns the SoftHSM module, the test is skipped without one
+func softHSMModule(t *testing.T) string {
+	t.Helper()
+	if module, ok := os.LookupEnv(softHSMModuleEnv); ok {
+		return module
+	}
+	for _, module := range m_softHSMModules {
+		if _, err := os.Stat(module); err == nil {
+			return module
+		}
+	}
+	t.Skipf("SoftHSM not found, set %s to run the pkcs11 tests", softHSMModuleEnv)
+	return ""
+}
+
+// testPKCS11KeyStore initializes a SoftHSM token in a temporary directory
+// and returns a keystore logged in to it
+func testPKCS11KeyStore(t *testing.T) *PKCS11KeyStore {
+	t.Helper()
+	module := softHSMModule(t)
+
+	dir := t.TempDir()
+	tokenDir := filepath.Join(dir, "tokens")
+	if err := os.Mkdir(tokenDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "softhsm2.conf")
+	data := "directories.tokendir = " + tokenDir + "\nobjectstore.backend = file\nlog.level = ERROR\n"
+	if err := os.WriteFile(config, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOFTHSM2_CONF", config)
+	t.Setenv(PKCS11MODULE, module)
+	t.Setenv(PKCS11TOKENLABEL, testTokenLabel)
+
+	previous := m_pkcs11Dir
+	m_pkcs11Dir = filepath.Join(dir, PKCS11DirName)
+	t.Cleanup(func() { m_pkcs11Dir = previous })
+	useTestKeyMetadata(t)
+
+	initSoftHSMToken(t, module)
+
+	ks := &PKCS11KeyStore{passwords: &Passwords{password: passwordSource{set: true, password: []byte(testTokenPin)}}}
+	if err := ks.open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ks.Lock)
+	return ks
+}
+
+// initSoftHSMToken initializes the free slot of SoftHSM as the test token
+// with the test PIN
+func initSoftHSMToken(t *testing.T, module string) {
+	t.Helper()
+
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		t.Fatalf("loading %s failed", module)
+	}
+	defer ctx.Destroy()
+	if err := ctx.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Finalize()
+
+	slots, err := ctx.GetSlotList(false)
+	if err != nil || len(slots) == 0 {
+		t.Fatalf("no SoftHSM slot: %v", err)
+	}
+	if err := ctx.InitToken(slots[0], testTokenPin, testTokenLabel); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := openTokenSession(ctx, testTokenLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.CloseSession(session)
+	if err := ctx.Login(session, pkcs11.CKU_SO, testTokenPin); err != nil {
+		t.Fatal(err)
+	}
+	if err := ctx.InitPIN(session, testTokenPin); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Logout(session)
+}
+
+// checkTokenKey checks that the token key keyName is publicKey and signs
+// for its address
+func checkTokenKey(t *testing.T, ks *PKCS11KeyStore, keyName string, publicKey *ecdsa.PublicKey) {
+	t.Helper()
+
+	tokenKey, err := ks.PublicKey(keyName)
+	if err != nil {
+		t.Fatalf("PublicKey(%s): %v", keyName, err)
+	}
+	if crypto.PubkeyToAddress(*tokenKey) != crypto.PubkeyToAddress(*publicKey) {
+		t.Errorf("%s is not the expected key", keyName)
+	}
+
+	hash := crypto.Keccak256([]byte("registration"))
+	signature, err := ks.SignHash(keyName, hash)
+	if err != nil {
+		t.Fatalf("SignHash(%s): %v", keyName, err)
+	}
+	signer, err := crypto.SigToPub(hash, signature)
+	if err != nil || crypto.PubkeyToAddress(*signer) != crypto.PubkeyToAddress(*publicKey) {
+		t.Errorf("signature of %s does not recover its address: %v", keyName, err)
+	}
+}
+
+func TestPKCS11GenerateSign(t *testing.T) {
+	ks := testPKCS11KeyStore(t)
+
+	keyName, err := ks.Create("watchtower1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyName != "watchtower1" {
+		t.Errorf("Create = %s, want watchtower1", keyName)
+	}
+	metadata, ok, err := GetKeyMetadata(KeyTypePKCS11, keyName)
+	if err != nil || !ok {
+		t.Fatalf("no metadata for the created key: %v", err)
+	}
+	publicKey, err := ks.PublicKey(keyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(*publicKey) != metadata.Address {
+		t.Errorf("address of the created key = %s, metadata has %s", crypto.PubkeyToAddress(*publicKey), metadata.Address)
+	}
+	checkTokenKey(t, ks, keyName, publicKey)
+
+	// an unnamed key is labelled with its address
+	unnamed, err := ks.Create("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err = ks.PublicKey(unnamed)
+	if err != nil || crypto.PubkeyToAddress(*publicKey).String() != unnamed {
+		t.Errorf("unnamed key is labelled %s: %v", unnamed, err)
+	}
+
+	if _, err := ks.Load(keyName); err == nil {
+		t.Error("a token key was loaded")
+	}
+}
+
+func TestPKCS11SaveReplacesKey(t *testing.T) {
+	ks := testPKCS11KeyStore(t)
+
+	first, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.Save("operator", first, false); err != nil {
+		t.Fatal(err)
+	}
+	checkTokenKey(t, ks, "operator", &first.PublicKey)
+
+	if err := ks.Save("operator", second, false); err != nil {
+		t.Fatal(err)
+	}
+	checkTokenKey(t, ks, "operator", &second.PublicKey)
+
+	// the replaced key is gone and no pending key is left
+	handles, err := ks.findObjects("", pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handles) != 2 {
+		t.Errorf("token holds %d objects, want the 2 of the key", len(handles))
+	}
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keyNames, []string{"operator"}) {
+		t.Errorf("KeyNames = %v, want [operator]", keyNames)
+	}
+}
+
+func TestPKCS11KeyNamesSkipsPendingKeys(t *testing.T) {
+	ks := testPKCS11KeyStore(t)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Save("watchtower1", key, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// a key left pending by an interrupted replace is not listed, and is
+	// cleared by the next save
+	pending, _ := crypto.GenerateKey()
+	if err := ks.Save("watchtower2", pending, false); err != nil {
+		t.Fatal(err)
+	}
+	handles, err := ks.findObjects("watchtower2", pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_LABEL, "watchtower2"+pkcs11PendingSuffix)}
+	for _, handle := range handles {
+		if err := ks.ctx.SetAttributeValue(ks.session, handle, label); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keyNames, []string{"watchtower1"}) {
+		t.Errorf("KeyNames = %v, want [watchtower1]", keyNames)
+	}
+
+	if err := ks.Save("watchtower2", key, false); err != nil {
+		t.Fatal(err)
+	}
+	handles, err = ks.findObjects("watchtower2"+pkcs11PendingSuffix, pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY)
+	if err != nil || len(handles) != 0 {
+		t.Errorf("pending key was not cleared: %d objects, %v", len(handles), err)
+	}
+	checkTokenKey(t, ks, "watchtower2", &key.PublicKey)
+}
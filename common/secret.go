@@ -0,0 +1,63 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SecretHex is a private key kept as bytes, so it can be wiped once it is
+// not needed anymore. In JSON it is plain hex without the 0x prefix
+type SecretHex []byte
+
+func (s SecretHex) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(s))
+}
+
+func (s *SecretHex) UnmarshalJSON(data []byte) error {
+	var hexKey string
+	if err := json.Unmarshal(data, &hexKey); err != nil {
+		return err
+	}
+
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return err
+	}
+	*s = key
+	return nil
+}
+
+// WipeBytes overwrites a buffer that held a password or a key
+func WipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// WipePrivateKey overwrites the private scalar of key, the key can't be
+// used afterwards
+func WipePrivateKey(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+
+	words := key.D.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	key.D.SetInt64(0)
+}
+
+// EncodePrivateKeyHex returns the hex encoding of key, the caller wipes it
+// once written
+func EncodePrivateKeyHex(key *ecdsa.PrivateKey) []byte {
+	keyBytes := crypto.FromECDSA(key)
+	defer WipeBytes(keyBytes)
+
+	hexKey := make([]byte, hex.EncodedLen(len(keyBytes)))
+	hex.Encode(hexKey, keyBytes)
+	return hexKey
+}
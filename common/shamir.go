@@ -0,0 +1,129 @@
+package wc_common
+
+import (
+	"crypto/rand"
+)
+
+// Shamir secret sharing over GF(2^8), every byte of the secret is shared
+// with its own random polynomial and shares are evaluated at x = 1..n
+
+var m_gfExp [510]byte
+var m_gfLog [256]byte
+
+func init() {
+	// 3 is a generator of the multiplicative group of GF(2^8) with the
+	// AES polynomial x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		m_gfExp[i] = x
+		m_gfLog[x] = byte(i)
+		x ^= gfMulNoTable(x, 2)
+	}
+	for i := 255; i < len(m_gfExp); i++ {
+		m_gfExp[i] = m_gfExp[i-255]
+	}
+}
+
+func gfMulNoTable(a, b byte) byte {
+	var product byte
+	for b != 0 {
+		if b&1 != 0 {
+			product ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return product
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return m_gfExp[int(m_gfLog[a])+int(m_gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return m_gfExp[int(m_gfLog[a])+255-int(m_gfLog[b])]
+}
+
+// SplitSecret returns shares shares of secret, any threshold of which
+// rebuild it. Share i is evaluated at x = i+1
+func SplitSecret(secret []byte, shares int, threshold int) ([][]byte, error) {
+	if threshold < 2 || shares < threshold || shares > 255 {
+		return nil, ErrInvalidShareCount
+	}
+
+	result := make([][]byte, shares)
+	for i := range result {
+		result[i] = make([]byte, len(secret))
+	}
+
+	coefficients := make([]byte, threshold)
+	for b, secretByte := range secret {
+		coefficients[0] = secretByte
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+
+		for i := range result {
+			x := byte(i + 1)
+			// Horner's method
+			var y byte
+			for c := threshold - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			result[i][b] = y
+		}
+	}
+
+	for i := range coefficients {
+		coefficients[i] = 0
+	}
+
+	return result, nil
+}
+
+// CombineShares rebuilds the secret from shares evaluated at xs using
+// Lagrange interpolation at x = 0
+func CombineShares(xs []byte, shares [][]byte) ([]byte, error) {
+	if len(xs) != len(shares) || len(shares) < 2 {
+		return nil, ErrInvalidShareCount
+	}
+
+	for i := range xs {
+		if xs[i] == 0 || len(shares[i]) != len(shares[0]) {
+			return nil, ErrInvalidShare
+		}
+		for j := 0; j < i; j++ {
+			if xs[i] == xs[j] {
+				return nil, ErrDuplicateShare
+			}
+		}
+	}
+
+	secret := make([]byte, len(shares[0]))
+	for i := range xs {
+		// basis polynomial of share i evaluated at 0
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfDiv(xs[j], xs[j]^xs[i]))
+		}
+
+		for b := range secret {
+			secret[b] ^= gfMul(shares[i][b], basis)
+		}
+	}
+
+	return secret, nil
+}
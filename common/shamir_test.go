@@ -0,0 +1,149 @@
+package wc_common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// splitKey splits a fresh key into key shares as backup does
+func splitKey(t *testing.T, shares int, threshold int) ([]byte, []KeyShare) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := crypto.FromECDSA(key)
+
+	secrets, err := SplitSecret(secret, shares, threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyShares := make([]KeyShare, shares)
+	for i, s := range secrets {
+		keyShares[i] = KeyShare{
+			Version:   KeyShareVersion,
+			Index:     byte(i + 1),
+			Threshold: threshold,
+			Shares:    shares,
+			Address:   crypto.PubkeyToAddress(key.PublicKey),
+			Share:     hex.EncodeToString(s),
+		}
+		keyShares[i].Checksum = keyShares[i].computeChecksum()
+	}
+	return secret, keyShares
+}
+
+func TestSplitCombineEveryThresholdSubset(t *testing.T) {
+	for _, scheme := range [][2]int{{2, 2}, {3, 2}, {5, 3}, {6, 4}} {
+		shares, threshold := scheme[0], scheme[1]
+		secret, keyShares := splitKey(t, shares, threshold)
+
+		for k := threshold; k <= shares; k++ {
+			forEachCombination(shares, k, func(subset []int) bool {
+				xs := make([]byte, len(subset))
+				ys := make([][]byte, len(subset))
+				selected := make([]KeyShare, len(subset))
+				for i, s := range subset {
+					xs[i] = keyShares[s].Index
+					ys[i], _ = hex.DecodeString(keyShares[s].Share)
+					selected[i] = keyShares[s]
+				}
+
+				combined, err := CombineShares(xs, ys)
+				if err != nil || !bytes.Equal(combined, secret) {
+					t.Errorf("%d of %d, shares %v: CombineShares did not rebuild the secret: %v", threshold, shares, subset, err)
+				}
+
+				combined, err = CombineKeyShares(selected)
+				if err != nil || !bytes.Equal(combined, secret) {
+					t.Errorf("%d of %d, shares %v: CombineKeyShares did not rebuild the secret: %v", threshold, shares, subset, err)
+				}
+				return false
+			})
+		}
+	}
+}
+
+func TestCombineBelowThreshold(t *testing.T) {
+	secret, keyShares := splitKey(t, 5, 3)
+
+	forEachCombination(5, 2, func(subset []int) bool {
+		selected := []KeyShare{keyShares[subset[0]], keyShares[subset[1]]}
+		if _, err := CombineKeyShares(selected); !errors.Is(err, ErrNotEnoughShares) {
+			t.Errorf("shares %v: error = %v, want ErrNotEnoughShares", subset, err)
+		}
+
+		xs := []byte{selected[0].Index, selected[1].Index}
+		ys := make([][]byte, 2)
+		ys[0], _ = hex.DecodeString(selected[0].Share)
+		ys[1], _ = hex.DecodeString(selected[1].Share)
+		if combined, err := CombineShares(xs, ys); err == nil && bytes.Equal(combined, secret) {
+			t.Errorf("shares %v: rebuilt the secret below the threshold", subset)
+		}
+		return false
+	})
+
+	if _, err := CombineShares([]byte{1}, [][]byte{{1}}); !errors.Is(err, ErrInvalidShareCount) {
+		t.Errorf("single share: error = %v, want ErrInvalidShareCount", err)
+	}
+}
+
+func TestCombineDuplicateShares(t *testing.T) {
+	_, keyShares := splitKey(t, 3, 2)
+
+	ys := make([][]byte, 2)
+	ys[0], _ = hex.DecodeString(keyShares[0].Share)
+	ys[1], _ = hex.DecodeString(keyShares[0].Share)
+	if _, err := CombineShares([]byte{1, 1}, ys); !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("CombineShares error = %v, want ErrDuplicateShare", err)
+	}
+
+	if _, err := CombineKeyShares([]KeyShare{keyShares[0], keyShares[0], keyShares[1]}); !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("CombineKeyShares error = %v, want ErrDuplicateShare", err)
+	}
+}
+
+func TestCorruptedShares(t *testing.T) {
+	_, keyShares := splitKey(t, 3, 2)
+	dir := t.TempDir()
+
+	// an edited share without a matching checksum is refused when read
+	edited := keyShares[0]
+	editedShare, _ := hex.DecodeString(edited.Share)
+	editedShare[0] ^= 0x01
+	edited.Share = hex.EncodeToString(editedShare)
+	data, _ := json.Marshal(edited)
+	shareFile := filepath.Join(dir, "edited.json")
+	if err := os.WriteFile(shareFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyShare(shareFile); !errors.Is(err, ErrInvalidShare) {
+		t.Errorf("ReadKeyShare error = %v, want ErrInvalidShare", err)
+	}
+
+	// a corrupted share with a recomputed checksum does not rebuild the
+	// address of the key
+	corrupted := keyShares[0]
+	share, _ := hex.DecodeString(corrupted.Share)
+	share[0] ^= 0xff
+	corrupted.Share = hex.EncodeToString(share)
+	corrupted.Checksum = corrupted.computeChecksum()
+	if _, err := CombineKeyShares([]KeyShare{corrupted, keyShares[1]}); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("CombineKeyShares error = %v, want ErrShareMismatch", err)
+	}
+
+	// shares of another key are refused
+	_, otherShares := splitKey(t, 3, 2)
+	if _, err := CombineKeyShares([]KeyShare{keyShares[0], otherShares[1]}); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("CombineKeyShares of two keys error = %v, want ErrShareMismatch", err)
+	}
+}
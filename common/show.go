@@ -0,0 +1,88 @@
+package wc_common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+// KeyInfo is the public part of a stored key as printed by keys show
+type KeyInfo struct {
+	Name      string         `json:"name"`
+	KeyType   string         `json:"key_type"`
+	Address   common.Address `json:"address"`
+	PublicKey string         `json:"public_key"`
+}
+
+func ShowCmd() *cli.Command {
+	var showCmd = &cli.Command{
+		Name:      "show",
+		Usage:     "show the address and public key of a key from local keystore",
+		UsageText: "show --key-name <keyName> [--json]",
+		Flags: []cli.Flag{
+			&KeyNameFlag,
+			&KeyStoreType,
+			&JSONFlag,
+		},
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.String("key-name") == "" {
+				return fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
+			}
+			return ShowKeyCmd(cCtx)
+		},
+	}
+	return showCmd
+}
+
+func ShowKeyCmd(cCtx *cli.Context) error {
+	keyName := cCtx.String("key-name")
+	keyType := cCtx.String("key-type")
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	info, err := GetKeyInfo(cCtx.Context, keyType, keyName)
+	if err != nil {
+		return err
+	}
+
+	if cCtx.Bool("json") {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Error encoding key info: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Println("Address    : ", info.Address.Hex())
+	fmt.Println("Public key : ", info.PublicKey)
+	return nil
+}
+
+// GetKeyInfo decrypts the key in memory, unless the keystore signs in
+// place, and returns only its public part
+func GetKeyInfo(ctx context.Context, keyType string, keyName string) (KeyInfo, error) {
+	keyStore, err := GetKeyStore(ctx, keyType)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	publicKey, err := LoadPublicKey(keyStore, keyName)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+
+	return KeyInfo{
+		Name:      keyName,
+		KeyType:   keyType,
+		Address:   crypto.PubkeyToAddress(*publicKey),
+		PublicKey: hexutil.Encode(crypto.FromECDSAPub(publicKey)),
+	}, nil
+}
@@ -12,41 +12,52 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-func GetLatestNonce(client *ethclient.Client, privateKey *ecdsa.PrivateKey) *big.Int {
+func GetLatestNonce(client *ethclient.Client, privateKey *ecdsa.PrivateKey) (*big.Int, error) {
 	senderAddress := GetPublicAddressFromPrivateKey(privateKey)
 	nonce, err := client.PendingNonceAt(context.Background(), senderAddress)
-	CheckError(err, "Pending nonce calculation failed")
+	if err != nil {
+		return nil, fmt.Errorf("Pending nonce calculation failed: %w", err)
+	}
 	nonceNew := big.NewInt(int64(nonce))
-	return nonceNew
+	return nonceNew, nil
 }
 
-func PrepareTransactionOptions(client *ethclient.Client, chainId big.Int, gasLimit uint64, privateKey *ecdsa.PrivateKey) *bind.TransactOpts {
+func PrepareTransactionOptions(client *ethclient.Client, chainId big.Int, gasLimit uint64, privateKey *ecdsa.PrivateKey) (*bind.TransactOpts, error) {
 
 	gasPrice, err := client.SuggestGasPrice(context.Background())
-	CheckError(err, "Gas price calculation failed")
+	if err != nil {
+		return nil, fmt.Errorf("Gas price calculation failed: %w", err)
+	}
 
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, &chainId)
-	CheckError(err, "Transactor creation failed")
+	if err != nil {
+		return nil, fmt.Errorf("Transactor creation failed: %w", err)
+	}
 
 	auth.Value = big.NewInt(0) // in wei
 	auth.GasLimit = gasLimit   // in units
 	auth.GasPrice = gasPrice
 
-	return auth
+	return auth, nil
 }
 
-func WaitForTransactionReceipt(client *ethclient.Client, txn *types.Transaction, timeout uint64) {
+// WaitForTransactionReceipt waits until txn is mined, a reverted
+// transaction returns ErrTransactionFailed
+func WaitForTransactionReceipt(client *ethclient.Client, txn *types.Transaction, timeout uint64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*(time.Second))
 
 	defer cancel()
 
 	receipt, err := bind.WaitMined(ctx, client, txn)
 	if err != nil {
-		CheckError(err, "Transaction failed")
-	} else if receipt.Status == 1 {
-		fmt.Println("Transaction executed successfully, logs are ...")
-		fmt.Println(receipt.Logs)
-	} else if receipt.Status == 0 {
-		FatalError("Transaction submitted successfully but failed to execute!")
+		return fmt.Errorf("Transaction failed: %w", err)
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("tx %s: %w", txn.Hash().Hex(), ErrTransactionFailed)
 	}
+
+	fmt.Println("Transaction executed successfully, logs are ...")
+	fmt.Println(receipt.Logs)
+	return nil
 }
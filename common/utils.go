@@ -32,39 +32,57 @@ type MountResult struct {
 	Filesystems []Filesystem `json:"filesystems"`
 }
 
-var password string
-
-func ConnectToUrl(url string) (*ethclient.Client, *big.Int) {
+func ConnectToUrl(url string) (*ethclient.Client, *big.Int, error) {
 	client, err := ethclient.Dial(url)
-	CheckError(err, "Connection to RPC failed")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Connection to RPC failed: %w", err)
+	}
 
 	id, err := client.ChainID(context.Background())
-	CheckError(err, "Unable to retrive chainID for : "+url)
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("Unable to retrive chainID for %s: %w", url, err)
+	}
 
 	fmt.Println("Connection successful : ", id)
 
-	return client, id
+	return client, id, nil
 }
 
-func GetECDSAPrivateKey(privateKeyString string) *ecdsa.PrivateKey {
-	ecdsaPrivateKey, err := crypto.HexToECDSA(privateKeyString)
-	CheckError(err, "Converting private key to ECDSA format failed")
-	return ecdsaPrivateKey
-}
+// GetChainConfig returns the contract addresses of a chain witnesschain is
+// deployed on, from the built-in networks and the user networks file
+func GetChainConfig(chainID *big.Int) (ChainConfig, error) {
+	networks, err := GetNetworks()
+	if err != nil {
+		return ChainConfig{}, err
+	}
 
-func GetPublicAddressFromPrivateKey(privateKey *ecdsa.PrivateKey) common.Address {
-	publicKeyECDSA, ok := privateKey.Public().(*ecdsa.PublicKey)
+	chainConfig, ok := networks[chainID.String()]
 	if !ok {
-		FatalError("Error casting public key to ECDSA")
+		return ChainConfig{}, fmt.Errorf("chain id %s is not a known network, add it with networks add: %w", chainID, ErrWrongChain)
+	}
+	return chainConfig, nil
+}
+
+func GetECDSAPrivateKey(privateKeyString string) (*ecdsa.PrivateKey, error) {
+	ecdsaPrivateKey, err := crypto.HexToECDSA(privateKeyString)
+	if err != nil {
+		return nil, fmt.Errorf("Converting private key to ECDSA format failed: %w", err)
 	}
+	return ecdsaPrivateKey, nil
+}
 
-	return crypto.PubkeyToAddress(*publicKeyECDSA)
+func GetPublicAddressFromPrivateKey(privateKey *ecdsa.PrivateKey) common.Address {
+	return crypto.PubkeyToAddress(privateKey.PublicKey)
 }
 
-func GetECDSAPrivateAndPublicKey(privateKeyString string) (*ecdsa.PrivateKey, common.Address) {
-	ecdsaPrivateKey := GetECDSAPrivateKey(privateKeyString)
+func GetECDSAPrivateAndPublicKey(privateKeyString string) (*ecdsa.PrivateKey, common.Address, error) {
+	ecdsaPrivateKey, err := GetECDSAPrivateKey(privateKeyString)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
 	ecdsaPublicKey := GetPublicAddressFromPrivateKey(ecdsaPrivateKey)
-	return ecdsaPrivateKey, ecdsaPublicKey
+	return ecdsaPrivateKey, ecdsaPublicKey, nil
 }
 
 func GetPaddedValue(value []byte) [32]byte {
@@ -75,10 +93,12 @@ func GetPaddedValue(value []byte) [32]byte {
 	return paddedValue
 }
 
-func CalculateExpiry(client *ethclient.Client, expectedExpiryDays uint64) *big.Int {
+func CalculateExpiry(client *ethclient.Client, expectedExpiryDays uint64) (*big.Int, error) {
 	// Get the latest block header
 	header, err := client.HeaderByNumber(context.Background(), nil)
-	CheckError(err, "Could not get HeaderByNumber")
+	if err != nil {
+		return nil, fmt.Errorf("Could not get HeaderByNumber: %w", err)
+	}
 
 	// Get the current timestamp from the latest block header
 	currentTimestamp := big.NewInt(int64(header.Time))
@@ -87,198 +107,304 @@ func CalculateExpiry(client *ethclient.Client, expectedExpiryDays uint64) *big.I
 	timeToElapse := big.NewInt(expiryInSeconds)
 
 	expiry := new(big.Int).Add(currentTimestamp, timeToElapse)
-	return expiry
+	return expiry, nil
 }
 
-func GenerateSalt() [32]byte {
+func GenerateSalt() ([32]byte, error) {
 	var salt [32]byte
 
 	// Generate random bytes
 	_, err := rand.Read(salt[:])
-	CheckError(err, "Generating salt failed")
+	if err != nil {
+		return salt, fmt.Errorf("Generating salt failed: %w", err)
+	}
 
-	return salt
+	return salt, nil
 }
 
-func ValidateAndMount() {
-	CheckIfGocryptfsIsInstalled()
+func ValidateAndMount(passwords *Passwords) error {
+	if err := CheckIfGocryptfsIsInstalled(); err != nil {
+		return err
+	}
 
 	if !ValidEncryptedDir() {
-		FatalErrorWithoutUnmount(fmt.Sprintf("%v: %s\n", ErrInvalidEncryptedDirectory,
-			" : check if "+m_goCryptFSConfig+" exist. Or try initiating again after deleting those directories"))
+		return fmt.Errorf("%w: %w: check if %s exist. Or try initiating again after deleting those directories",
+			ErrKeyStoreNotInitialized, ErrInvalidEncryptedDirectory, m_goCryptFSConfig)
 	}
 
-	if IsAlreadyMounted() {
-		if !m_retryMounting {
-			FatalErrorWithoutUnmount(m_gocryptfsDecDir + " already mounted")
-		}
+	alreadyMounted, err := IsAlreadyMounted()
+	if err != nil {
+		return err
+	}
+	if alreadyMounted && !m_retryMounting {
+		return fmt.Errorf("%s: %w", m_gocryptfsDecDir, ErrAlreadyMounted)
+	}
+
+	// asked once here, so the retries below don't prompt again
+	password, err := passwords.Password(true, "mount")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(password)
+
+	if !alreadyMounted {
+		return Mount(password)
+	}
 
-		fmt.Println("GoCryptFS filesystem already mounted")
-		for i := 0; i < MaxMountRetries; i++ {
-			fmt.Printf("Retrying in %v seconds\n", RetryPeriodInSeconds)
+	fmt.Println("GoCryptFS filesystem already mounted")
+	for i := 0; i < MaxMountRetries; i++ {
+		fmt.Printf("Retrying in %v seconds\n", RetryPeriodInSeconds)
 
-			// RetryPeriodInSeconds
-			time.Sleep(time.Duration(RetryPeriodInSeconds * uint(time.Second)))
-			Mount()
-			if m_isMounted {
-				return
-			}
+		// RetryPeriodInSeconds
+		time.Sleep(time.Duration(RetryPeriodInSeconds * uint(time.Second)))
+		if err := Mount(password); err != nil {
+			return err
+		}
+		if m_isMounted {
+			return nil
 		}
-		FatalErrorWithoutUnmount("Giving up, " + m_gocryptfsDecDir + " already mounted")
-	} else {
-		Mount()
 	}
+	return fmt.Errorf("Giving up, %s: %w", m_gocryptfsDecDir, ErrAlreadyMounted)
 }
 
-func Mount() {
-	if IsAlreadyMounted() {
-		return
+func Mount(password []byte) error {
+	alreadyMounted, err := IsAlreadyMounted()
+	if err != nil || alreadyMounted {
+		return err
 	}
 
 	mountCmd := exec.Command("gocryptfs", m_gocryptfsEncDir, m_gocryptfsDecDir)
-	RunCommandWithPassword(mountCmd, "mount", true)
+	if err := RunCommandWithPassword(mountCmd, "mount", password); err != nil {
+		return err
+	}
 
 	m_isMounted = true
+	return nil
 }
 
-func Unmount() {
+// Unmount unmounts the filesystem mounted by this process, if any. main
+// calls it on every exit path
+func Unmount() error {
 	if !m_isMounted {
-		return
+		return nil
 	}
 
 	umountCmd := exec.Command("fusermount", "-u", m_gocryptfsDecDir)
-	err := umountCmd.Run()
-	if err != nil {
-		CheckErrorWithoutUnmount(err, "Error unmounting GoCryptFS filesystem")
+	if err := umountCmd.Run(); err != nil {
+		return fmt.Errorf("Error unmounting GoCryptFS filesystem: %w", err)
 	}
 
 	m_isMounted = false
+	return nil
 }
 
-func IsAlreadyMounted() bool {
+func IsAlreadyMounted() (bool, error) {
 	cmd := exec.Command("findmnt", "-n", "-o", "TARGET", "--type", "fuse.gocryptfs", "-J")
 	output, err := cmd.CombinedOutput()
-	CheckError(err, "Error checking if filesystem is mounted. Output - "+string(output))
+	// findmnt exits with 1 and prints nothing when no filesystem matches
+	if err != nil && len(output) != 0 {
+		return false, fmt.Errorf("Error checking if filesystem is mounted. Output - %s: %w", output, err)
+	}
+	if len(output) == 0 {
+		return false, nil
+	}
 
 	var mountResult MountResult
-	err = json.Unmarshal(output, &mountResult)
+	if err := json.Unmarshal(output, &mountResult); err != nil {
+		return false, fmt.Errorf("Error checking if filesystem is mounted. Output - %s: %w", output, err)
+	}
 
-	CheckError(err, "Error checking if filesystem is mounted. Output - "+string(output))
+	absolutePath, err := filepath.Abs(m_gocryptfsDecDir)
+	if err != nil {
+		return false, fmt.Errorf("Error getting absolute path: %w", err)
+	}
 
 	for _, fs := range mountResult.Filesystems {
-		absolutePath, err := filepath.Abs(m_gocryptfsDecDir)
-		CheckError(err, "Error getting absolute path")
-
 		if absolutePath == fs.Target {
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
-func DirectoryExists(path string) bool {
+func DirectoryExists(path string) (bool, error) {
 	fileInfo, err := os.Stat(path)
 
 	if os.IsNotExist(err) {
-		return false
+		return false, nil
 	}
 
-	CheckError(err, "Error checking directory")
+	if err != nil {
+		return false, fmt.Errorf("Error checking directory: %w", err)
+	}
 
 	if fileInfo.Mode().IsRegular() {
-		CheckError(ErrNotADirectory, path)
+		return false, fmt.Errorf("%s: %w", path, ErrNotADirectory)
 	}
 
-	return true
+	return true, nil
 }
 
-func CreateDirectory(path string) {
-	fmt.Println("Creating directory: ", path)
-	err := os.MkdirAll(path, 0755)
-	CheckError(err, "Error creating directory")
+// EnsureDirectory creates path unless it already exists
+func EnsureDirectory(path string) error {
+	exists, err := DirectoryExists(path)
+	if err != nil || exists {
+		return err
+	}
+	return CreateDirectory(path)
 }
 
-func RunCommandWithPassword(cmd *exec.Cmd, desc string, insecure bool) {
-	fmt.Printf("Enter password to %s: ", desc)
-	if len(password) == 0 {
-		password = ReadHiddenInput()
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it over path, so an interrupted write never leaves a truncated file
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error creating temporary file: %w", err)
 	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
 
-	if !insecure {
-		ValidatePassword(password)
+	if _, err := tmpFile.Write(data); err != nil {
+		return fmt.Errorf("Error writing temporary file: %w", err)
 	}
 
-	cmdStdin, err := cmd.StdinPipe()
-	CheckError(err, "Error creating stdin pipe for "+desc)
+	if err := tmpFile.Chmod(perm); err != nil {
+		return fmt.Errorf("Error setting file permissions: %w", err)
+	}
 
-	err = cmd.Start()
-	CheckError(err, "Error starting command for "+desc)
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("Error syncing temporary file: %w", err)
+	}
 
-	_, err = cmdStdin.Write([]byte(password))
-	CheckError(err, "Error writing to command stdin for "+desc)
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("Error closing temporary file: %w", err)
+	}
 
-	err = cmdStdin.Close()
-	CheckError(err, "Error closing command stdin for "+desc)
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("Error replacing %s: %w", path, err)
+	}
+	return nil
+}
 
-	err = cmd.Wait()
-	CheckError(err, "Command failed for "+desc)
+func CreateDirectory(path string) error {
+	fmt.Println("Creating directory: ", path)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("Error creating directory: %w", err)
+	}
+	return nil
+}
+
+// RunCommandWithPassword writes password to the stdin of cmd, it is up to
+// the caller to wipe it
+func RunCommandWithPassword(cmd *exec.Cmd, desc string, password []byte) error {
+	cmdStdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("Error creating stdin pipe for %s: %w", desc, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Error starting command for %s: %w", desc, err)
+	}
+
+	_, err = cmdStdin.Write(password)
+	if closeErr := cmdStdin.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cmd.Wait()
+		return fmt.Errorf("Error writing to command stdin for %s: %w", desc, err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("Command failed for %s: %w", desc, err)
+	}
+	return nil
 }
 
 func AllowKeyOverwrite(fileLoc string) bool {
 	_, err := os.Stat(fileLoc)
 	if !os.IsNotExist(err) {
-		fmt.Printf("Key already exists, do you want to overwrite? (y/n): ")
-		var response string
-		fmt.Scanln(&response)
-
-		if strings.ToLower(response) != "y" {
-			return false
-		}
+		return ConfirmKeyOverwrite()
 	}
 
 	return true
 }
 
-func GetPasswordFromPrompt(insecure bool, desc string) string {
-	fmt.Printf("Enter password to %s: ", desc)
-	password := ReadHiddenInput()
-
-	if !insecure {
-		ValidatePassword(password)
+// PromptLine asks for one line on stdin. It reads byte by byte, so the
+// prompts that read stdin after it still get their input
+func PromptLine(desc string) string {
+	fmt.Printf("%s: ", desc)
+
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(b)
+		if n == 0 || err != nil || b[0] == '\n' {
+			break
+		}
+		line = append(line, b[0])
 	}
+	return strings.TrimSpace(string(line))
+}
+
+func ConfirmKeyOverwrite() bool {
+	fmt.Printf("Key already exists, do you want to overwrite? (y/n): ")
+	var response string
+	fmt.Scanln(&response)
+
+	return strings.ToLower(response) == "y"
+}
 
-	return password
+// GetPasswordFromPrompt returns the password of the password source of
+// the command, or asks for it. The caller wipes it once used
+func GetPasswordFromPrompt(ctx context.Context, insecure bool, desc string) ([]byte, error) {
+	return KeyStoresFromContext(ctx).Passwords().Password(insecure, desc)
 }
 
-func IsWatchtowerRegistered(watchtower common.Address, operatorRegistry *OperatorRegistry.OperatorRegistry) bool {
+// GetNewPasswordFromPrompt returns the password of --new-password-file, or
+// asks for it twice. The caller wipes it once used
+func GetNewPasswordFromPrompt(ctx context.Context, insecure bool, desc string) ([]byte, error) {
+	return KeyStoresFromContext(ctx).Passwords().NewPassword(insecure, desc)
+}
+
+func IsWatchtowerRegistered(watchtower common.Address, operatorRegistry *OperatorRegistry.OperatorRegistry) (bool, error) {
 	registered, err := operatorRegistry.IsValidWatchtower(&bind.CallOpts{}, watchtower)
-	CheckError(err, "Error checking if watchtower is already registered")
-	return registered
+	if err != nil {
+		return false, fmt.Errorf("Error checking if watchtower is already registered: %w", err)
+	}
+	return registered, nil
 }
 
-func IsOperatorWhitelisted(operator common.Address, operatorRegistry *OperatorRegistry.OperatorRegistry) bool {
+func IsOperatorWhitelisted(operator common.Address, operatorRegistry *OperatorRegistry.OperatorRegistry) (bool, error) {
 	active, err := operatorRegistry.IsActiveOperator(&bind.CallOpts{}, operator)
-	CheckError(err, "Error checking if operator is whitelisted")
-	return active
+	if err != nil {
+		return false, fmt.Errorf("Error checking if operator is whitelisted: %w", err)
+	}
+	return active, nil
 }
 
-func IsOperatorRegistered(witnessHubAddress common.Address, operator common.Address, avsDirectory *AvsDirectory.AvsDirectory) bool {
+func IsOperatorRegistered(witnessHubAddress common.Address, operator common.Address, avsDirectory *AvsDirectory.AvsDirectory) (bool, error) {
 	status, err := avsDirectory.AvsOperatorStatus(&bind.CallOpts{}, witnessHubAddress, operator)
-	CheckError(err, "Checking operator status failed")
-	return status != 0
+	if err != nil {
+		return false, fmt.Errorf("Checking operator status failed: %w", err)
+	}
+	return status != 0, nil
 }
 
-func CheckIfGocryptfsIsInstalled() {
+func CheckIfGocryptfsIsInstalled() error {
 	cmd := exec.Command("gocryptfs", "--version")
-	err := cmd.Run()
-
-	CheckErrorWithoutUnmount(err, "Check if gocryptfs is installed")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %v", ErrGocryptfsNotInstalled, err)
+	}
+	return nil
 }
 
+// GetUserHomeDir returns the home directory of the user, or the current
+// directory when it is unknown, so the package can be imported anywhere
 func GetUserHomeDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		FatalErrorWithoutUnmount("Error getting user home directory : " + err.Error())
+		return "."
 	}
 	return home
 }
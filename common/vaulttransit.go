@@ -0,0 +1,526 @@
+package wc_common
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	VAULTADDR        = "VAULT_ADDR"
+	VAULTTOKEN       = "VAULT_TOKEN"
+	VAULTNAMESPACE   = "VAULT_NAMESPACE"
+	VAULTROLEID      = "VAULT_ROLE_ID"
+	VAULTSECRETID    = "VAULT_SECRET_ID"
+	VAULTTRANSITPATH = "VAULT_TRANSIT_MOUNT"
+)
+
+var m_vaultTransitDir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, VaultTransitDirName)
+
+// VaultTransitConfig is the Vault server the keystore uses, written by keys
+// init. The token and the AppRole secret id are never written, they are
+// read from VAULT_TOKEN and VAULT_SECRET_ID or asked like a password
+type VaultTransitConfig struct {
+	Address      string `json:"address"`
+	Namespace    string `json:"namespace,omitempty"`
+	Mount        string `json:"mount"`
+	KeyType      string `json:"key_type"`
+	AuthMethod   string `json:"auth_method"`
+	AppRoleMount string `json:"approle_mount,omitempty"`
+	RoleID       string `json:"role_id,omitempty"`
+}
+
+// VaultTransitKeyStore keeps the keys in a Vault Transit style secrets
+// engine, which signs hashes with keys that never leave Vault. Load and
+// Export fail and the keys are used through SignHash. The Vault token is
+// kept until Lock
+type VaultTransitKeyStore struct {
+	passwords *Passwords
+	config    VaultTransitConfig
+	token     []byte
+	client    *http.Client
+}
+
+func init() {
+	RegisterKeyStore(KeyTypeVaultTransit, func(options KeyStoreOptions) KeyStore {
+		return &VaultTransitKeyStore{passwords: options.Passwords}
+	})
+}
+
+func (ks *VaultTransitKeyStore) Init(insecure bool) error {
+	config, err := readVaultTransitConfig()
+	if err != nil {
+		return err
+	}
+
+	if config.Address == "" {
+		config.Address = PromptLine("Address of the Vault server")
+	}
+	if config.AuthMethod == "" {
+		config.AuthMethod = PromptLine("Auth method (token/approle)")
+	}
+	if config.AuthMethod == VaultAuthAppRole && config.RoleID == "" {
+		config.RoleID = PromptLine("AppRole role id")
+	}
+	if config.AuthMethod != VaultAuthToken && config.AuthMethod != VaultAuthAppRole {
+		return fmt.Errorf("%s: %w", config.AuthMethod, ErrInvalidVaultAuth)
+	}
+
+	// check the server, the credentials and the mount before keeping them
+	ks.Lock()
+	ks.config = config
+	if err := ks.login(); err != nil {
+		return err
+	}
+	if _, err := ks.KeyNames(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error encoding vault transit config: %w", err)
+	}
+	if err := EnsureDirectory(m_vaultTransitDir); err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(GetVaultTransitConfigFile(), data, 0600); err != nil {
+		return err
+	}
+
+	fmt.Println("Init keystore done")
+	return nil
+}
+
+func (ks *VaultTransitKeyStore) Create(keyName string, insecure bool) (string, error) {
+	if keyName == "" {
+		return "", fmt.Errorf("Required flag \"key-name\" not set: %w", ErrEmptyKeyName)
+	}
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	if err := ks.open(); err != nil {
+		return "", err
+	}
+
+	// a transit key can't be replaced, only rotated inside Vault
+	exists, err := HasKey(ks, keyName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("Error creating key %s: %w", keyName, ErrKeyExists)
+	}
+
+	// the built-in transit engine answers 400 for the unknown key type
+	body := map[string]interface{}{"type": ks.config.KeyType, "exportable": false}
+	err = ks.request(http.MethodPost, ks.keyURL("keys", keyName), body, nil)
+	if errors.Is(err, ErrVaultBadRequest) {
+		return "", fmt.Errorf("Error creating key %s, the engine at %s does not offer key type %s, see docs/vault-transit.md: %w", keyName, ks.config.Mount, ks.config.KeyType, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error creating key %s: %w", keyName, err)
+	}
+
+	publicKey, err := ks.PublicKey(keyName)
+	if err != nil {
+		return "", err
+	}
+	address := crypto.PubkeyToAddress(*publicKey)
+
+	if err := RecordKeyMetadata(KeyTypeVaultTransit, keyName, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+func (ks *VaultTransitKeyStore) Import(keyName string, insecure bool) (string, error) {
+	return "", fmt.Errorf("Error importing key, create it in vault instead: %w", ErrNotSupported)
+}
+
+func (ks *VaultTransitKeyStore) Export(keyName string) error {
+	return fmt.Errorf("Error exporting key %s: %w", keyName, ErrKeyNotExtractable)
+}
+
+// Delete allows the deletion of the key in Vault and deletes it, Vault
+// refuses it when the policy of the token does not allow it
+func (ks *VaultTransitKeyStore) Delete(keyName string) error {
+	if err := ks.open(); err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{"deletion_allowed": true}
+	if err := ks.request(http.MethodPost, ks.keyURL("keys", keyName)+"/config", body, nil); err != nil {
+		return fmt.Errorf("Error deleting key %s: %w", keyName, err)
+	}
+	if err := ks.request(http.MethodDelete, ks.keyURL("keys", keyName), nil, nil); err != nil {
+		return fmt.Errorf("Error deleting key %s: %w", keyName, err)
+	}
+	return nil
+}
+
+func (ks *VaultTransitKeyStore) List() error {
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		return err
+	}
+
+	path := strings.TrimRight(ks.config.Address, "/") + "/v1/" + ks.config.Mount + "/keys"
+
+	var keys []KeyListEntry
+	for _, keyName := range keyNames {
+		entry := KeyListEntry{Name: keyName, Path: path + "/" + keyName}
+		if metadata, ok, err := GetKeyMetadata(KeyTypeVaultTransit, keyName); err != nil {
+			return err
+		} else if ok {
+			entry.Created = metadata.Created
+		}
+		keys = append(keys, entry)
+	}
+
+	return PrintKeyList(KeyTypeVaultTransit, path, keys)
+}
+
+func (ks *VaultTransitKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	return nil, fmt.Errorf("Error loading key %s: %w", keyPath, ErrKeyNotExtractable)
+}
+
+func (ks *VaultTransitKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	return fmt.Errorf("Error storing key %s, create it in vault instead: %w", keyName, ErrNotSupported)
+}
+
+// KeyNames returns the names of the keys of the transit mount, a mount
+// without keys answers 404
+func (ks *VaultTransitKeyStore) KeyNames() ([]string, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := ks.request("LIST", ks.keyURL("keys", ""), nil, &response)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, fmt.Errorf("Error listing keys: %w", err)
+	}
+
+	sort.Strings(response.Data.Keys)
+	return response.Data.Keys, nil
+}
+
+func (ks *VaultTransitKeyStore) ChangePassword(keyName string, insecure bool) error {
+	return fmt.Errorf("Error changing password, vault credentials are managed in vault: %w", ErrNotSupported)
+}
+
+// UseKeyPath does nothing, transit keys are referenced by name
+func (ks *VaultTransitKeyStore) UseKeyPath(keyPath string) {
+}
+
+func (ks *VaultTransitKeyStore) KeyPath(keyName string) string {
+	return keyName
+}
+
+func (ks *VaultTransitKeyStore) Lock() {
+	WipeBytes(ks.token)
+	ks.token = nil
+}
+
+// PublicKey reads the public key of the latest version of keyName
+func (ks *VaultTransitKeyStore) PublicKey(keyName string) (*ecdsa.PublicKey, error) {
+	return ks.publicKey(keyName, 0)
+}
+
+// SignHash signs a 32 byte hash in Vault. The signature is [R || S || V]
+// with a low S and V as 0/1, as crypto.Sign returns
+func (ks *VaultTransitKeyStore) SignHash(keyName string, hash []byte) ([]byte, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(hash),
+		"prehashed":            true,
+		"marshaling_algorithm": "asn1",
+	}
+	var response struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := ks.request(http.MethodPost, ks.keyURL("sign", keyName), body, &response); err != nil {
+		return nil, fmt.Errorf("Error signing with key %s: %w", keyName, err)
+	}
+
+	// vault:v<version>:<base64 DER signature>
+	parts := strings.SplitN(response.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf("Error reading signature of key %s: %w", keyName, ErrInvalidSignature)
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[1], "v%d", &version); err != nil {
+		return nil, fmt.Errorf("Error reading signature version of key %s: %w", keyName, ErrInvalidSignature)
+	}
+	der, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding signature of key %s: %w", keyName, err)
+	}
+
+	var rs struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &rs); err != nil {
+		return nil, fmt.Errorf("Error decoding signature of key %s: %w", keyName, err)
+	}
+	signature := make([]byte, 64)
+	rs.R.FillBytes(signature[:32])
+	rs.S.FillBytes(signature[32:])
+
+	// the key may have been rotated in vault, recover with the version that
+	// signed
+	publicKey, err := ks.publicKey(keyName, version)
+	if err != nil {
+		return nil, err
+	}
+	return RecoverableSignature(hash, signature, publicKey)
+}
+
+func GetVaultTransitConfigFile() string {
+	return filepath.Join(m_vaultTransitDir, VaultTransitConfigName)
+}
+
+func readVaultTransitConfig() (VaultTransitConfig, error) {
+	config := VaultTransitConfig{Mount: DefaultTransitMount, KeyType: DefaultTransitKeyType, AppRoleMount: DefaultAppRoleMount}
+
+	data, err := os.ReadFile(GetVaultTransitConfigFile())
+	if err != nil && !os.IsNotExist(err) {
+		return config, fmt.Errorf("Error reading vault transit config: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			return config, fmt.Errorf("Error unmarshaling vault transit config: %w", err)
+		}
+	}
+
+	for env, field := range map[string]*string{VAULTADDR: &config.Address, VAULTNAMESPACE: &config.Namespace, VAULTTRANSITPATH: &config.Mount, VAULTROLEID: &config.RoleID} {
+		if value, ok := os.LookupEnv(env); ok {
+			*field = value
+		}
+	}
+	return config, nil
+}
+
+// open reads the config and logs in on first use
+func (ks *VaultTransitKeyStore) open() error {
+	if ks.token != nil {
+		return nil
+	}
+
+	config, err := readVaultTransitConfig()
+	if err != nil {
+		return err
+	}
+	if config.Address == "" || config.AuthMethod == "" {
+		return fmt.Errorf("Run keys init -t %s or set %s: %w: %w", KeyTypeVaultTransit, VAULTADDR, ErrKeyStoreNotInitialized, ErrInvalidVaultAuth)
+	}
+
+	ks.config = config
+	return ks.login()
+}
+
+// login reads the token, or logs in with the AppRole secret id. Both come
+// from the environment or are asked like a password
+func (ks *VaultTransitKeyStore) login() error {
+	if ks.client == nil {
+		ks.client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	switch ks.config.AuthMethod {
+	case VaultAuthToken:
+		if token, ok := os.LookupEnv(VAULTTOKEN); ok {
+			ks.token = []byte(token)
+			return nil
+		}
+		token, err := ks.passwords.Password(true, "log in to vault with a token")
+		if err != nil {
+			return err
+		}
+		ks.token = token
+		return nil
+	case VaultAuthAppRole:
+		secretID := []byte(os.Getenv(VAULTSECRETID))
+		if len(secretID) == 0 {
+			var err error
+			if secretID, err = ks.passwords.Password(true, "log in to vault with the AppRole secret id"); err != nil {
+				return err
+			}
+		}
+		defer WipeBytes(secretID)
+
+		var response struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		body := map[string]interface{}{"role_id": ks.config.RoleID, "secret_id": string(secretID)}
+		loginURL := ks.url("auth", ks.config.AppRoleMount, "login")
+		if err := ks.request(http.MethodPost, loginURL, body, &response); err != nil {
+			return fmt.Errorf("Error logging in to vault with AppRole: %w", ErrInvalidPassword)
+		}
+		ks.token = []byte(response.Auth.ClientToken)
+		return nil
+	default:
+		return fmt.Errorf("%s: %w", ks.config.AuthMethod, ErrInvalidVaultAuth)
+	}
+}
+
+// publicKey reads the public key of a key version, version 0 is the latest
+func (ks *VaultTransitKeyStore) publicKey(keyName string, version int) (*ecdsa.PublicKey, error) {
+	if err := ks.open(); err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := ks.request(http.MethodGet, ks.keyURL("keys", keyName), nil, &response); err != nil {
+		return nil, fmt.Errorf("Error reading key %s: %w", keyName, err)
+	}
+
+	if version == 0 {
+		version = response.Data.LatestVersion
+	}
+	key, ok := response.Data.Keys[fmt.Sprint(version)]
+	if !ok {
+		return nil, fmt.Errorf("Error reading key %s version %d: %w", keyName, version, ErrKeyNotFound)
+	}
+
+	publicKey, err := ParseSecp256k1PublicKeyPEM([]byte(key.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key %s of type %s: %w", keyName, response.Data.Type, err)
+	}
+	return publicKey, nil
+}
+
+// ParseSecp256k1PublicKeyPEM reads a PEM encoded SubjectPublicKeyInfo of a
+// secp256k1 key, which crypto/x509 does not support
+func ParseSecp256k1PublicKeyPEM(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrUnsupportedCurve
+	}
+
+	var info struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(block.Bytes, &info); err != nil {
+		return nil, ErrUnsupportedCurve
+	}
+	if !info.Algorithm.Parameters.Equal(asn1.ObjectIdentifier{1, 3, 132, 0, 10}) {
+		return nil, ErrUnsupportedCurve
+	}
+
+	return crypto.UnmarshalPubkey(info.PublicKey.Bytes)
+}
+
+func (ks *VaultTransitKeyStore) keyURL(endpoint string, keyName string) string {
+	if keyName == "" {
+		return ks.url(ks.config.Mount, endpoint)
+	}
+	return ks.url(ks.config.Mount, endpoint, keyName)
+}
+
+func (ks *VaultTransitKeyStore) url(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = url.PathEscape(strings.Trim(part, "/"))
+	}
+	return strings.TrimRight(ks.config.Address, "/") + "/v1/" + strings.Join(parts, "/")
+}
+
+// request sends a Vault API request, an error response returns the errors
+// Vault gives
+func (ks *VaultTransitKeyStore) request(method string, url string, body interface{}, response interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("Error encoding vault request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("Error creating vault request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if ks.token != nil {
+		request.Header.Set("X-Vault-Token", string(ks.token))
+	}
+	if ks.config.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", ks.config.Namespace)
+	}
+
+	resp, err := ks.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("Error connecting to vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("Error reading vault response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("vault answered status %d: %w", resp.StatusCode, ErrKeyNotFound)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var vaultErrors struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &vaultErrors)
+		err := fmt.Errorf("vault answered status %d: %s", resp.StatusCode, strings.Join(vaultErrors.Errors, ", "))
+		if resp.StatusCode == http.StatusBadRequest {
+			err = fmt.Errorf("%w: %w", err, ErrVaultBadRequest)
+		}
+		return err
+	}
+
+	if response == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("Error unmarshaling vault response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// transitFake answers the keys, sign and AppRole login endpoints of the
+// Transit API with secp256k1 keys, as a compatible engine would
+type transitFake struct {
+	t       *testing.T
+	keyType string
+	// keys holds the versions of each key, version 1 first
+	keys map[string][]*ecdsa.PrivateKey
+	// status, when set, answers every request with that status
+	status int
+}
+
+func newTransitFake(t *testing.T) (*transitFake, *httptest.Server) {
+	fake := &transitFake{t: t, keyType: DefaultTransitKeyType, keys: map[string][]*ecdsa.PrivateKey{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *transitFake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.status != 0 {
+		f.fail(w, f.status, "permission denied")
+		return
+	}
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var body struct {
+			RoleID   string `json:"role_id"`
+			SecretID string `json:"secret_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.RoleID != "role" || body.SecretID != "secret" {
+			f.fail(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		f.reply(w, map[string]interface{}{"auth": map[string]string{"client_token": "approle-token"}})
+		return
+	}
+	if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "approle-token" {
+		f.fail(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case r.Method == "LIST" && r.URL.Path == "/v1/transit/keys":
+		if len(f.keys) == 0 {
+			f.fail(w, http.StatusNotFound, "")
+			return
+		}
+		var keyNames []string
+		for keyName := range f.keys {
+			keyNames = append(keyNames, keyName)
+		}
+		sort.Strings(keyNames)
+		f.reply(w, map[string]interface{}{"data": map[string]interface{}{"keys": keyNames}})
+	case strings.HasPrefix(r.URL.Path, "/v1/transit/keys/"):
+		f.serveKey(w, r, strings.TrimPrefix(r.URL.Path, "/v1/transit/keys/"))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/transit/sign/"):
+		f.serveSign(w, r, strings.TrimPrefix(r.URL.Path, "/v1/transit/sign/"))
+	default:
+		f.fail(w, http.StatusNotFound, "")
+	}
+}
+
+func (f *transitFake) serveKey(w http.ResponseWriter, r *http.Request, keyName string) {
+	switch r.Method {
+	case http.MethodPost:
+		var body struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Type != f.keyType {
+			f.fail(w, http.StatusBadRequest, "unknown key type: "+body.Type)
+			return
+		}
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			f.t.Error(err)
+		}
+		f.keys[keyName] = append(f.keys[keyName], key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		versions, ok := f.keys[keyName]
+		if !ok {
+			f.fail(w, http.StatusNotFound, "")
+			return
+		}
+		keys := map[string]interface{}{}
+		for i, key := range versions {
+			keys[fmt.Sprint(i+1)] = map[string]string{"public_key": transitPublicKeyPEM(f.t, &key.PublicKey)}
+		}
+		f.reply(w, map[string]interface{}{"data": map[string]interface{}{"type": f.keyType, "latest_version": len(versions), "keys": keys}})
+	default:
+		f.fail(w, http.StatusMethodNotAllowed, "")
+	}
+}
+
+// serveSign signs with the latest version, the signature has the high S
+// of the two valid ones
+func (f *transitFake) serveSign(w http.ResponseWriter, r *http.Request, keyName string) {
+	versions, ok := f.keys[keyName]
+	if !ok {
+		f.fail(w, http.StatusBadRequest, "signing key not found")
+		return
+	}
+
+	var body struct {
+		Input     string `json:"input"`
+		Prehashed bool   `json:"prehashed"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	hash, err := base64.StdEncoding.DecodeString(body.Input)
+	if err != nil || !body.Prehashed || len(hash) != 32 {
+		f.fail(w, http.StatusBadRequest, "invalid input")
+		return
+	}
+
+	signature, err := crypto.Sign(hash, versions[len(versions)-1])
+	if err != nil {
+		f.t.Error(err)
+	}
+	rs := struct{ R, S *big.Int }{new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64])}
+	rs.S.Sub(crypto.S256().Params().N, rs.S)
+	der, err := asn1.Marshal(rs)
+	if err != nil {
+		f.t.Error(err)
+	}
+	vaultSignature := fmt.Sprintf("vault:v%d:%s", len(versions), base64.StdEncoding.EncodeToString(der))
+	f.reply(w, map[string]interface{}{"data": map[string]string{"signature": vaultSignature}})
+}
+
+func (f *transitFake) reply(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *transitFake) fail(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	if message != "" {
+		json.NewEncoder(w).Encode(map[string][]string{"errors": {message}})
+	}
+}
+
+// transitPublicKeyPEM encodes publicKey as the SubjectPublicKeyInfo Vault
+// returns
+func transitPublicKeyPEM(t *testing.T, publicKey *ecdsa.PublicKey) string {
+	var info struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.ObjectIdentifier
+		}
+		PublicKey asn1.BitString
+	}
+	info.Algorithm.Algorithm = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	info.Algorithm.Parameters = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+	point := crypto.FromECDSAPub(publicKey)
+	info.PublicKey = asn1.BitString{Bytes: point, BitLength: 8 * len(point)}
+
+	der, err := asn1.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// testVaultTransitKeyStore returns a keystore logged in to server with a
+// token
+func testVaultTransitKeyStore(t *testing.T, server *httptest.Server) *VaultTransitKeyStore {
+	useTestKeyMetadata(t)
+	return &VaultTransitKeyStore{
+		config: VaultTransitConfig{Address: server.URL, Mount: DefaultTransitMount, KeyType: DefaultTransitKeyType, AuthMethod: VaultAuthToken},
+		token:  []byte("root"),
+		client: server.Client(),
+	}
+}
+
+func TestVaultTransitCreateSign(t *testing.T) {
+	fake, server := newTransitFake(t)
+	ks := testVaultTransitKeyStore(t, server)
+
+	if keyNames, err := ks.KeyNames(); err != nil || len(keyNames) != 0 {
+		t.Errorf("KeyNames of an empty mount = %v, %v", keyNames, err)
+	}
+
+	keyName, err := ks.Create("operator", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(fake.keys["operator"][0].PublicKey)
+	metadata, ok, err := GetKeyMetadata(KeyTypeVaultTransit, keyName)
+	if err != nil || !ok || metadata.Address != address {
+		t.Errorf("metadata of the created key = %+v, %v, %v", metadata, ok, err)
+	}
+	if _, err := ks.Create("operator", false); !errors.Is(err, ErrKeyExists) {
+		t.Errorf("Create of an existing key error = %v, want ErrKeyExists", err)
+	}
+
+	// the high S of the engine is turned into the low S ethereum accepts,
+	// with the V that recovers the key
+	hash := crypto.Keccak256([]byte("registration"))
+	signature, err := ks.SignHash(keyName, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(signature[32:64]).Cmp(new(big.Int).Rsh(crypto.S256().Params().N, 1)) > 0 {
+		t.Error("signature has a high S")
+	}
+	if !crypto.ValidateSignatureValues(signature[64], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:64]), true) {
+		t.Error("signature is not valid for ethereum")
+	}
+	signer, err := crypto.SigToPub(hash, signature)
+	if err != nil || crypto.PubkeyToAddress(*signer) != address {
+		t.Errorf("signature does not recover the key address: %v", err)
+	}
+
+	// a rotated key is recovered with the version that signed
+	rotated, _ := crypto.GenerateKey()
+	fake.keys["operator"] = append(fake.keys["operator"], rotated)
+	signature, err = ks.SignHash(keyName, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err = crypto.SigToPub(hash, signature)
+	if err != nil || crypto.PubkeyToAddress(*signer) != crypto.PubkeyToAddress(rotated.PublicKey) {
+		t.Errorf("signature of the rotated key does not recover its address: %v", err)
+	}
+
+	if _, err := ks.Load(keyName); !errors.Is(err, ErrKeyNotExtractable) {
+		t.Errorf("Load error = %v, want ErrKeyNotExtractable", err)
+	}
+}
+
+func TestVaultTransitAppRoleLogin(t *testing.T) {
+	_, server := newTransitFake(t)
+	useTestKeyMetadata(t)
+	t.Setenv(VAULTSECRETID, "secret")
+
+	ks := &VaultTransitKeyStore{config: VaultTransitConfig{
+		Address:      server.URL,
+		Mount:        DefaultTransitMount,
+		KeyType:      DefaultTransitKeyType,
+		AuthMethod:   VaultAuthAppRole,
+		AppRoleMount: DefaultAppRoleMount,
+		RoleID:       "role",
+	}}
+	if err := ks.login(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Create("watchtower1", false); err != nil {
+		t.Errorf("Create with the AppRole token: %v", err)
+	}
+
+	ks.Lock()
+	ks.config.RoleID = "other"
+	if err := ks.login(); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("login error = %v, want ErrInvalidPassword", err)
+	}
+}
+
+func TestVaultTransitErrors(t *testing.T) {
+	fake, server := newTransitFake(t)
+	ks := testVaultTransitKeyStore(t, server)
+
+	// the built-in engine does not know the secp256k1 key type
+	fake.keyType = "ecdsa-p256"
+	if _, err := ks.Create("operator", false); !errors.Is(err, ErrVaultBadRequest) {
+		t.Errorf("Create of an unknown key type error = %v, want ErrVaultBadRequest", err)
+	}
+
+	if _, err := ks.PublicKey("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("PublicKey of a missing key error = %v, want ErrKeyNotFound", err)
+	}
+	if _, err := ks.SignHash("missing", crypto.Keccak256([]byte("registration"))); err == nil {
+		t.Error("SignHash with a missing key succeeded")
+	}
+
+	// the errors of vault are given back
+	fake.status = http.StatusForbidden
+	if _, err := ks.KeyNames(); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("KeyNames error = %v, want the vault error", err)
+	}
+
+	fake.status = 0
+	ks.token = []byte("wrong")
+	if _, err := ks.Create("operator", false); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Create with a wrong token error = %v, want status 403", err)
+	}
+
+	server.Close()
+	ks.token = []byte("root")
+	if _, err := ks.KeyNames(); err == nil {
+		t.Error("KeyNames with the server down succeeded")
+	}
+}
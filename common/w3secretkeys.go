@@ -0,0 +1,294 @@
+package wc_common
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sdkEcdsa "github.com/Layr-Labs/eigensdk-go/crypto/ecdsa"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const W3SECRETPASSPHRASE = "W3SECRETPASSPHRASE"
+
+var m_w3SecretKeyDir string = filepath.Join(GetUserHomeDir(), WitnesschainCLIPath, W3SecretKeyDirName)
+
+// W3SecretKeyStore keeps every key in its own web3 secret storage file. The
+// password is kept until Lock, so a keystore keeps a single password and
+// it is asked only once per command
+type W3SecretKeyStore struct {
+	passwords *Passwords
+	password  []byte
+}
+
+func init() {
+	RegisterKeyStore(KeyTypeW3SecretKey, func(options KeyStoreOptions) KeyStore {
+		return &W3SecretKeyStore{passwords: options.Passwords}
+	})
+}
+
+func (ks *W3SecretKeyStore) Init(insecure bool) error {
+	if err := EnsureDirectory(m_w3SecretKeyDir); err != nil {
+		return err
+	}
+	fmt.Println("Init keystore done")
+	return nil
+}
+
+func (ks *W3SecretKeyStore) Create(keyName string, insecure bool) (string, error) {
+	privateKey, err := GenerateRandomKey()
+	if err != nil {
+		return "", err
+	}
+	defer WipePrivateKey(privateKey)
+
+	address := GetPublicAddressFromPrivateKey(privateKey)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyFileName := keyName + W3SecretKeySuffixName
+	keyFile := filepath.Join(m_w3SecretKeyDir, keyFileName)
+
+	if !AllowKeyOverwrite(keyFile) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privateKey, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeW3SecretKey, keyName, address, KeySourceCreated); err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Created key: %s\n", keyName)
+	return keyName, nil
+}
+
+func (ks *W3SecretKeyStore) Import(keyName string, insecure bool) (string, error) {
+	hexKey, err := GetPrivateKeyFromUser()
+	if err != nil {
+		return "", err
+	}
+	defer WipeBytes(hexKey)
+
+	privateKeyPair, err := ParsePrivateKey(hexKey)
+	if err != nil {
+		return "", fmt.Errorf("Error converting hex string to ECDSA private key: %w", err)
+	}
+	defer WipePrivateKey(privateKeyPair)
+
+	address := GetPublicAddressFromPrivateKey(privateKeyPair)
+
+	if keyName == "" {
+		keyName = address.String()
+	}
+
+	if err := ValidateKeyName(keyName); err != nil {
+		return "", fmt.Errorf("Error validating key name: %w", err)
+	}
+
+	keyFileName := keyName + W3SecretKeySuffixName
+	keyFile := filepath.Join(m_w3SecretKeyDir, keyFileName)
+
+	if !AllowKeyOverwrite(keyFile) {
+		return "", nil
+	}
+
+	if err := ks.Save(keyName, privateKeyPair, insecure); err != nil {
+		return "", err
+	}
+	if err := RecordKeyMetadata(KeyTypeW3SecretKey, keyName, address, KeySourceImported); err != nil {
+		return "", err
+	}
+	fmt.Printf("Imported key: %s %s\n", keyName, address.Hex())
+	return keyName, nil
+}
+
+func (ks *W3SecretKeyStore) Export(keyName string) error {
+	key, err := ks.Load(keyName)
+	if err != nil {
+		return err
+	}
+	defer WipePrivateKey(key)
+
+	fmt.Println("Public key : ", GetPublicAddressFromPrivateKey(key))
+	fmt.Println("Private key : ", hex.EncodeToString(crypto.FromECDSA(key)))
+	return nil
+}
+
+func (ks *W3SecretKeyStore) Delete(keyName string) error {
+	if err := os.Remove(GetSanitizedW3SecretKeyName(keyName)); err != nil {
+		return fmt.Errorf("Error deleting key: %w", err)
+	}
+	return nil
+}
+
+func (ks *W3SecretKeyStore) List() error {
+	return ListKeyDir(KeyTypeW3SecretKey, m_w3SecretKeyDir, W3SecretKeySuffixName)
+}
+
+func (ks *W3SecretKeyStore) Load(keyPath string) (*ecdsa.PrivateKey, error) {
+	if ks.password == nil {
+		password, err := ks.passwords.Password(true, "unlock web3 secret storage keys")
+		if err != nil {
+			return nil, err
+		}
+		ks.password = password
+	}
+
+	return GetW3SecretStoragePrivateKey(keyNameFromPath(keyPath), ks.password)
+}
+
+// Save encrypts the key with the password already used in this process to
+// read keys, so a keystore keeps a single password, and asks for one
+// otherwise
+func (ks *W3SecretKeyStore) Save(keyName string, privateKey *ecdsa.PrivateKey, insecure bool) error {
+	if ks.password == nil {
+		password, err := ks.passwords.Password(insecure, "encrypt web3 secret storage keys")
+		if err != nil {
+			return err
+		}
+		ks.password = password
+	}
+
+	keyFile := filepath.Join(m_w3SecretKeyDir, keyName+W3SecretKeySuffixName)
+	if err := sdkEcdsa.WriteKey(keyFile, privateKey, string(ks.password)); err != nil {
+		return fmt.Errorf("Error Writing ecdsa key: %w", err)
+	}
+	return nil
+}
+
+func (ks *W3SecretKeyStore) KeyNames() ([]string, error) {
+	files, err := os.ReadDir(m_w3SecretKeyDir)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Error reading directory %s, run keys init --key-type %s: %w", m_w3SecretKeyDir, KeyTypeW3SecretKey, ErrKeyStoreNotInitialized)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading directory: %w", err)
+	}
+
+	var keyNames []string
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), W3SecretKeySuffixName) {
+			continue
+		}
+		keyNames = append(keyNames, strings.TrimSuffix(file.Name(), W3SecretKeySuffixName))
+	}
+	return keyNames, nil
+}
+
+func (ks *W3SecretKeyStore) ChangePassword(keyName string, insecure bool) error {
+	keyNames, err := ks.KeyNames()
+	if err != nil {
+		return err
+	}
+	if keyName != "" {
+		keyNames = []string{keyName}
+	}
+
+	oldPassword, err := ks.passwords.Password(true, "unlock web3 secret storage keys")
+	if err != nil {
+		return err
+	}
+	defer WipeBytes(oldPassword)
+
+	// read every key before writing any, so a wrong password changes nothing
+	keys := make([]*ecdsa.PrivateKey, len(keyNames))
+	for i, name := range keyNames {
+		keys[i], err = GetW3SecretStoragePrivateKey(name, oldPassword)
+		if err != nil {
+			return err
+		}
+		defer WipePrivateKey(keys[i])
+	}
+
+	newPassword, err := ks.passwords.NewPassword(insecure, "encrypt web3 secret storage keys")
+	if err != nil {
+		return err
+	}
+
+	for i, name := range keyNames {
+		keyFile := GetSanitizedW3SecretKeyName(name)
+		tmpFile := keyFile + ".tmp"
+
+		if err := sdkEcdsa.WriteKey(tmpFile, keys[i], string(newPassword)); err != nil {
+			WipeBytes(newPassword)
+			return fmt.Errorf("Error Writing ecdsa key: %w", err)
+		}
+
+		// the new file replaces the old one only once it is known to decrypt
+		if _, err := sdkEcdsa.ReadKey(tmpFile, string(newPassword)); err != nil {
+			os.Remove(tmpFile)
+			WipeBytes(newPassword)
+			return fmt.Errorf("Error verifying re-encrypted key %s: %w", name, err)
+		}
+
+		if err := os.Rename(tmpFile, keyFile); err != nil {
+			WipeBytes(newPassword)
+			return fmt.Errorf("Error replacing key %s: %w", name, err)
+		}
+
+		fmt.Printf("Changed password of key: %s\n", name)
+	}
+
+	WipeBytes(ks.password)
+	ks.password = newPassword
+	return nil
+}
+
+func (ks *W3SecretKeyStore) UseKeyPath(keyPath string) {
+	dir, file := filepath.Split(keyPath)
+	if file != keyPath {
+		m_w3SecretKeyDir = dir
+		m_isFullPath = true
+	}
+	fmt.Printf("Using the key path : %s\n", m_w3SecretKeyDir)
+}
+
+func (ks *W3SecretKeyStore) KeyPath(keyName string) string {
+	return filepath.Join(m_w3SecretKeyDir, keyName+W3SecretKeySuffixName)
+}
+
+func (ks *W3SecretKeyStore) Lock() {
+	WipeBytes(ks.password)
+	ks.password = nil
+}
+
+// GetW3SecretStoragePrivateKey decrypts a key file, a wrong password
+// returns ErrInvalidPassword
+func GetW3SecretStoragePrivateKey(keyName string, password []byte) (*ecdsa.PrivateKey, error) {
+	key, err := sdkEcdsa.ReadKey(GetSanitizedW3SecretKeyName(keyName), string(password))
+	if errors.Is(err, keystore.ErrDecrypt) {
+		err = ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading ecdsa key %s: %w", keyName, err)
+	}
+
+	return key, nil
+}
+
+func GetSanitizedW3SecretKeyName(keyName string) string {
+	keyFileName := keyName
+	if len(filepath.Ext(keyName)) == 0 {
+		keyFileName = keyName + W3SecretKeySuffixName
+	}
+
+	keyFile := keyFileName
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(m_w3SecretKeyDir, keyFileName)
+	}
+
+	return keyFile
+}
@@ -1,12 +1,15 @@
 package operator_config
 
 import (
+	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/big"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -19,6 +22,9 @@ type OperatorConfig struct {
 	WatchtowerPrivateKeysHex []string         `json:"watchtower_private_keys"`
 	WatchtowerAddresses      []common.Address `json:"watchtower_addresses"`
 	WatchtowerEncryptedKeys  []string         `json:"watchtower_encrypted_keys"`
+	WatchtowerHDIndexes      []uint32         `json:"watchtower_hd_indexes"`
+	HDSeedName               string           `json:"hd_seed"`
+	HDDerivationPath         string           `json:"hd_derivation_path"`
 	OperatorPrivateKeyHex    string           `json:"operator_private_key"`
 	OperatorAddress          common.Address   `json:"operator_address"`
 	OperatorEncryptedKey     string           `json:"operator_encrypted_key"`
@@ -32,54 +38,167 @@ type OperatorConfig struct {
 	WatchtowerPrivateKeys    []*ecdsa.PrivateKey
 	OperatorPrivateKey       *ecdsa.PrivateKey
 	ChainID                  *big.Int
+	// key names of the keys that sign in place, like PKCS#11 keys, which
+	// have no private key in the config, by address
+	WatchtowerKeyNames map[common.Address]string
+	OperatorKeyName    string
 }
 
-func GetConfigFromContext(cCtx *cli.Context) *OperatorConfig {
+// GetRPCConfigFromContext reads the config file and resolves its secret
+// references without loading any key, for commands that only read the
+// chain
+func GetRPCConfigFromContext(cCtx *cli.Context) (*OperatorConfig, error) {
 	configFilePath := cCtx.String("config-file")
 	fmt.Printf("Using config file path : %s\n", configFilePath)
+	if profile := cCtx.String("profile"); profile != "" {
+		fmt.Printf("Using profile : %s\n", profile)
+	}
 
-	data, err := os.ReadFile(configFilePath)
-	wc_common.CheckError(err, "Error reading json file")
+	data, err := ReadConfigFile(configFilePath, cCtx.String("profile"))
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse the json data into a struct
 	var config OperatorConfig = OperatorConfig{ExpiryInDays: 1, TxReceiptTimeout: 300, GasLimit: 300000}
-	err = json.Unmarshal(data, &config)
-	wc_common.CheckError(err, "Error unmarshaling json data")
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling json data: %w", err)
+	}
 
 	SetDefaultValues(&config)
 
-	if len(config.WatchtowerEncryptedKeys) != 0 {
-		// get the path from the first key, as others should be same
-		// will not work with different paths
+	if rawKeys := RawKeyFields(&config); len(rawKeys) != 0 {
+		if cCtx.Bool(wc_common.StrictSecretsFlag.Name) {
+			return nil, fmt.Errorf("%s: %w", strings.Join(rawKeys, ", "), wc_common.ErrRawKeyInConfig)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s has raw private keys in %s, use env:, file: or keystore: references\n", configFilePath, strings.Join(rawKeys, ", "))
+	}
+
+	var secretErr error
+	ResolveSecrets(cCtx.Context, &config, func(field string, err error) {
+		if secretErr == nil {
+			secretErr = fmt.Errorf("Error resolving %s: %w", field, err)
+		}
+	})
+	if secretErr != nil {
+		return nil, secretErr
+	}
+
+	return &config, nil
+}
+
+// GetConfigFromContext reads the config file and loads its keys, it fails
+// when there is no operator key or address
+func GetConfigFromContext(cCtx *cli.Context) (*OperatorConfig, error) {
+	config, err := GetRPCConfigFromContext(cCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadKeys(cCtx, config); err != nil {
+		config.Wipe()
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadKeys loads the keys of config, or reads the address of the keys that
+// sign in place
+func loadKeys(cCtx *cli.Context, config *OperatorConfig) error {
+	// get the path from the first key, as others should be same
+	// will not work with different paths
+	keyPaths := config.WatchtowerEncryptedKeys
+	if len(keyPaths) == 0 && config.OperatorEncryptedKey != "" {
+		keyPaths = []string{config.OperatorEncryptedKey}
+	}
+	if len(keyPaths) != 0 {
 		wc_common.RetryMounting()
-		wc_common.ProcessConfigKeyPath(config.WatchtowerEncryptedKeys[0], config.KeyType)
+		if err := wc_common.ProcessConfigKeyPath(cCtx.Context, keyPaths[0], config.KeyType); err != nil {
+			return err
+		}
 		wc_common.UseEncryptedKeys(config.KeyType)
 	}
 
+	// watchtower_addresses set together with keys must list their
+	// addresses, the addresses are then the ones of the keys
+	addresses := config.WatchtowerAddresses
+	config.WatchtowerAddresses = nil
+
 	if len(config.WatchtowerPrivateKeysHex) != 0 {
 		for _, privKey := range config.WatchtowerPrivateKeysHex {
-			fmt.Println(privKey)
 			key, err := crypto.HexToECDSA(privKey)
-			wc_common.CheckError(err, "unable to convert watchtower privatekey")
+			if err != nil {
+				return fmt.Errorf("unable to convert watchtower privatekey: %w", err)
+			}
 			config.WatchtowerAddresses = append(config.WatchtowerAddresses, crypto.PubkeyToAddress(key.PublicKey))
 			config.WatchtowerPrivateKeys = append(config.WatchtowerPrivateKeys, key)
 		}
 	}
 
+	keySigner, signInPlace := wc_common.GetKeySigner(cCtx.Context, config.KeyType)
+
 	if len(config.WatchtowerEncryptedKeys) != 0 {
 		for _, keyPath := range config.WatchtowerEncryptedKeys {
-			privKey, err := wc_common.LoadPrivateKey(keyPath, config.KeyType)
-			wc_common.CheckError(err, "unable to load encrypted keys")
+			if signInPlace {
+				publicKey, err := keySigner.PublicKey(keyPath)
+				if err != nil {
+					return fmt.Errorf("unable to load encrypted keys: %w", err)
+				}
+
+				address := crypto.PubkeyToAddress(*publicKey)
+				if config.WatchtowerKeyNames == nil {
+					config.WatchtowerKeyNames = map[common.Address]string{}
+				}
+				config.WatchtowerKeyNames[address] = keyPath
+				config.WatchtowerAddresses = append(config.WatchtowerAddresses, address)
+				continue
+			}
+
+			privKey, err := wc_common.LoadPrivateKey(cCtx.Context, keyPath, config.KeyType)
+			if err != nil {
+				return fmt.Errorf("unable to load encrypted keys: %w", err)
+			}
+
+			config.WatchtowerPrivateKeys = append(config.WatchtowerPrivateKeys, privKey)
+			config.WatchtowerAddresses = append(config.WatchtowerAddresses, crypto.PubkeyToAddress(privKey.PublicKey))
+		}
+	}
+
+	if len(config.WatchtowerHDIndexes) != 0 {
+		seed, err := wc_common.LoadSeed(cCtx.Context, config.HDSeedName)
+		if err != nil {
+			return err
+		}
+		for _, index := range config.WatchtowerHDIndexes {
+			privKey, err := wc_common.DeriveKeyFromSeed(seed, config.HDDerivationPath, index)
+			if err != nil {
+				wc_common.WipeBytes(seed)
+				return err
+			}
 
 			config.WatchtowerPrivateKeys = append(config.WatchtowerPrivateKeys, privKey)
 			config.WatchtowerAddresses = append(config.WatchtowerAddresses, crypto.PubkeyToAddress(privKey.PublicKey))
 		}
+		wc_common.WipeBytes(seed)
+	}
+
+	if len(config.WatchtowerAddresses) == 0 {
+		config.WatchtowerAddresses = addresses
+	} else if err := checkWatchtowerAddresses(addresses, config.WatchtowerAddresses); err != nil {
+		return err
 	}
 
-	if len(config.OperatorEncryptedKey) != 0 {
-		priv, err := wc_common.LoadPrivateKey(config.OperatorEncryptedKey, config.KeyType)
+	if len(config.OperatorEncryptedKey) != 0 && signInPlace {
+		publicKey, err := keySigner.PublicKey(config.OperatorEncryptedKey)
+		if err != nil {
+			return fmt.Errorf("unable to retive operator privateKey: %w", err)
+		}
+		config.OperatorAddress = crypto.PubkeyToAddress(*publicKey)
+		config.OperatorKeyName = config.OperatorEncryptedKey
+	} else if len(config.OperatorEncryptedKey) != 0 {
+		priv, err := wc_common.LoadPrivateKey(cCtx.Context, config.OperatorEncryptedKey, config.KeyType)
 		if err != nil {
-			log.Fatal("unable to retive operator privateKey")
+			return fmt.Errorf("unable to retive operator privateKey: %w", err)
 		}
 		config.OperatorAddress = crypto.PubkeyToAddress(priv.PublicKey)
 		config.OperatorPrivateKey = priv
@@ -87,16 +206,95 @@ func GetConfigFromContext(cCtx *cli.Context) *OperatorConfig {
 
 	if len(config.OperatorPrivateKeyHex) != 0 {
 		priv, err := crypto.HexToECDSA(config.OperatorPrivateKeyHex)
-		wc_common.CheckError(err, "unable to convert privateKey")
+		if err != nil {
+			return fmt.Errorf("unable to convert privateKey: %w", err)
+		}
+		// the raw key is used over the encrypted one
+		wc_common.WipePrivateKey(config.OperatorPrivateKey)
 		config.OperatorAddress = crypto.PubkeyToAddress(priv.PublicKey)
 		config.OperatorPrivateKey = priv
 	}
 
 	if config.OperatorAddress.Cmp(common.Address{0}) == 0 {
-		panic("operatorAddress is zero")
+		return wc_common.ErrZeroOperatorAddress
+	}
+
+	return nil
+}
+
+// checkWatchtowerAddresses checks that addresses, when set, are the
+// addresses of the watchtower keys in the order of the keys
+func checkWatchtowerAddresses(addresses []common.Address, keyAddresses []common.Address) error {
+	if len(addresses) == 0 {
+		return nil
+	}
+	if len(addresses) != len(keyAddresses) {
+		return fmt.Errorf("watchtower_addresses has %d addresses but the config has %d watchtower keys: %w", len(addresses), len(keyAddresses), wc_common.ErrAddressMismatch)
+	}
+	for i := range addresses {
+		if addresses[i] != keyAddresses[i] {
+			return fmt.Errorf("watchtower_addresses[%d] is %s but watchtower key %d is %s: %w", i, addresses[i].Hex(), i, keyAddresses[i].Hex(), wc_common.ErrAddressMismatch)
+		}
+	}
+	return nil
+}
+
+// Wipe clears the private keys loaded with the config, the raw, encrypted
+// and HD derived ones
+func (config *OperatorConfig) Wipe() {
+	wc_common.WipePrivateKey(config.OperatorPrivateKey)
+	for _, key := range config.WatchtowerPrivateKeys {
+		wc_common.WipePrivateKey(key)
+	}
+}
+
+// MarshalConfig encodes the fields of config that are set, in the order of
+// OperatorConfig and with the json names the config file is read with
+func MarshalConfig(config *OperatorConfig) ([]byte, error) {
+	var data bytes.Buffer
+	data.WriteString("{")
+	configValue := reflect.ValueOf(config).Elem()
+	for i := 0; i < configValue.NumField(); i++ {
+		name := jsonName(configValue.Type().Field(i))
+		if name == "" || configValue.Field(i).IsZero() {
+			continue
+		}
+		value, err := json.MarshalIndent(configValue.Field(i).Interface(), "  ", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("Error encoding %s: %w", name, err)
+		}
+		if data.Len() > 1 {
+			data.WriteString(",")
+		}
+		fmt.Fprintf(&data, "\n  %q: %s", name, value)
 	}
+	data.WriteString("\n}\n")
+	return data.Bytes(), nil
+}
 
-	return &config
+// GetOperatorAddress returns operator_address, or the address of the
+// operator key read from the key metadata, so that the key is only
+// decrypted when it has none. It is the zero address without an operator
+func GetOperatorAddress(ctx context.Context, config *OperatorConfig) (common.Address, error) {
+	switch {
+	case config.OperatorAddress != (common.Address{}):
+		return config.OperatorAddress, nil
+	case config.OperatorPrivateKeyHex != "":
+		key, err := crypto.HexToECDSA(config.OperatorPrivateKeyHex)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("unable to convert privateKey: %w", err)
+		}
+		defer wc_common.WipePrivateKey(key)
+		return crypto.PubkeyToAddress(key.PublicKey), nil
+	case config.OperatorEncryptedKey != "":
+		keyStore, err := wc_common.GetKeyStore(ctx, config.KeyType)
+		if err != nil {
+			return common.Address{}, err
+		}
+		keyStore.UseKeyPath(config.OperatorEncryptedKey)
+		return wc_common.GetKeyAddress(keyStore, config.KeyType, config.OperatorEncryptedKey)
+	}
+	return common.Address{}, nil
 }
 
 func SetDefaultValues(config *OperatorConfig) {
@@ -115,4 +313,12 @@ func SetDefaultValues(config *OperatorConfig) {
 	if config.KeyType == "" {
 		config.KeyType = wc_common.KeyTypeW3SecretKey
 	}
+
+	if config.HDSeedName == "" {
+		config.HDSeedName = wc_common.DefaultSeedName
+	}
+
+	if config.HDDerivationPath == "" {
+		config.HDDerivationPath = wc_common.DefaultHDPath
+	}
 }
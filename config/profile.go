@@ -0,0 +1,134 @@
+package operator_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// ProfilesField holds the named profiles of a config file. Every other top
+// level field is the base section the profiles inherit from
+const ProfilesField = "profiles"
+
+const hiddenValue = "<hidden>"
+
+// ReadConfigFile returns the fields of the base section of a config file,
+// with the fields of profile over them when profile is set
+func ReadConfigFile(configFilePath string, profile string) ([]byte, error) {
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading json file: %w", err)
+	}
+	return MergeProfile(data, profile)
+}
+
+// MergeProfile returns the fields of the base section of data with the
+// fields of profile over them. A profile replaces whole fields, lists are
+// not appended to
+func MergeProfile(data []byte, profile string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling json data: %w", err)
+	}
+
+	merged := map[string]json.RawMessage{}
+	for name, value := range fields {
+		if name != ProfilesField {
+			merged[canonicalFieldName(name)] = value
+		}
+	}
+
+	if profile != "" {
+		profiles := map[string]map[string]json.RawMessage{}
+		if raw, ok := fields[ProfilesField]; ok {
+			if err := json.Unmarshal(raw, &profiles); err != nil {
+				return nil, fmt.Errorf("Error unmarshaling profiles: %w", err)
+			}
+		}
+
+		profileFields, ok := profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("profile %s: %w", profile, wc_common.ErrUnknownProfile)
+		}
+		for name, value := range profileFields {
+			merged[canonicalFieldName(name)] = value
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding json data: %w", err)
+	}
+	return data, nil
+}
+
+// HideSecrets returns the fields of a merged config with its raw keys
+// hidden, and its urls cut to the host as they often hold an api key.
+// Secret references are kept as they are
+func HideSecrets(data []byte) ([]byte, error) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling json data: %w", err)
+	}
+
+	for name, value := range fields {
+		switch name {
+		case "operator_private_key":
+			fields[name] = hideKey(value)
+		case "watchtower_private_keys":
+			if keys, ok := value.([]interface{}); ok {
+				for i := range keys {
+					keys[i] = hideKey(keys[i])
+				}
+			}
+		case "eth_rpc_url", "proof_submission_rpc_urL", "external_signer_endpoint":
+			if rawUrl, ok := value.(string); ok && !IsSecretRef(rawUrl) {
+				fields[name] = HideUrl(rawUrl)
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding json data: %w", err)
+	}
+	return data, nil
+}
+
+func hideKey(value interface{}) interface{} {
+	if ref, ok := value.(string); ok && IsSecretRef(ref) {
+		return ref
+	}
+	return hiddenValue
+}
+
+// HideUrl keeps the scheme and the host of a url, and hides its user, path
+// and query
+func HideUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return hiddenValue
+	}
+
+	hidden := u.Scheme + "://" + u.Host
+	if u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+		hidden += "/" + hiddenValue
+	}
+	return hidden
+}
+
+// canonicalFieldName returns the json name of a config field, which
+// encoding/json matches case insensitively, so that a profile replaces the
+// field of the base section whatever its case
+func canonicalFieldName(name string) string {
+	index, ok := configFieldNames()[strings.ToLower(name)]
+	if !ok {
+		return name
+	}
+	return jsonName(reflect.TypeOf(OperatorConfig{}).Field(index))
+}
@@ -0,0 +1,150 @@
+package operator_config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// IsSecretRef reports whether a config value is a reference to a secret,
+// env:VAR, file:path or keystore:name, and not the secret itself
+func IsSecretRef(value string) bool {
+	for _, prefix := range []string{wc_common.SecretRefEnv, wc_common.SecretRefFile, wc_common.SecretRefKeyStore} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RawKeyFields returns the key fields of config that hold a raw key rather
+// than a reference
+func RawKeyFields(config *OperatorConfig) []string {
+	var fields []string
+	if config.OperatorPrivateKeyHex != "" && !IsSecretRef(config.OperatorPrivateKeyHex) {
+		fields = append(fields, "operator_private_key")
+	}
+	for i, key := range config.WatchtowerPrivateKeysHex {
+		if !IsSecretRef(key) {
+			fields = append(fields, fmt.Sprintf("watchtower_private_keys[%d]", i))
+		}
+	}
+	return fields
+}
+
+// ResolveSecrets replaces the references of the secret fields with their
+// values. A keystore reference in a key field moves the key to the
+// encrypted keys of the config, so that keys which sign in place work
+// too, and is refused in the other fields. Every field that fails is
+// passed to report, the fields that resolve are still replaced
+func ResolveSecrets(ctx context.Context, config *OperatorConfig, report func(field string, err error)) {
+	if IsSecretRef(config.OperatorPrivateKeyHex) {
+		value, keyPath, err := resolveKey(ctx, config, config.OperatorPrivateKeyHex)
+		switch {
+		case err != nil:
+			report("operator_private_key", err)
+		case keyPath != "" && config.OperatorEncryptedKey != "":
+			report("operator_private_key", fmt.Errorf("%s conflicts with operator_encrypted_key: %w", config.OperatorPrivateKeyHex, wc_common.ErrInvalidSecretRef))
+		case keyPath != "":
+			config.OperatorEncryptedKey = keyPath
+		}
+		config.OperatorPrivateKeyHex = value
+	}
+
+	var keys []string
+	for i, key := range config.WatchtowerPrivateKeysHex {
+		if !IsSecretRef(key) {
+			keys = append(keys, key)
+			continue
+		}
+
+		value, keyPath, err := resolveKey(ctx, config, key)
+		if err != nil {
+			report(fmt.Sprintf("watchtower_private_keys[%d]", i), err)
+			continue
+		}
+		if keyPath != "" {
+			config.WatchtowerEncryptedKeys = append(config.WatchtowerEncryptedKeys, keyPath)
+			continue
+		}
+		keys = append(keys, value)
+	}
+	config.WatchtowerPrivateKeysHex = keys
+
+	urls := []struct {
+		field string
+		value *string
+	}{
+		{"eth_rpc_url", &config.EthRPCUrl},
+		{"proof_submission_rpc_urL", &config.ProofSubmissionRPC},
+		{"external_signer_endpoint", &config.Endpoint},
+	}
+	for _, urlField := range urls {
+		if !IsSecretRef(*urlField.value) {
+			continue
+		}
+		if strings.HasPrefix(*urlField.value, wc_common.SecretRefKeyStore) {
+			report(urlField.field, fmt.Errorf("%s: keystore references are only for keys: %w", *urlField.value, wc_common.ErrInvalidSecretRef))
+			*urlField.value = ""
+			continue
+		}
+
+		value, err := resolveValue(*urlField.value)
+		if err != nil {
+			report(urlField.field, err)
+		}
+		*urlField.value = value
+	}
+}
+
+// resolveKey returns the value of an env or file reference, or the path
+// of the key of a keystore reference in the keystore of the config
+func resolveKey(ctx context.Context, config *OperatorConfig, ref string) (string, string, error) {
+	if !strings.HasPrefix(ref, wc_common.SecretRefKeyStore) {
+		value, err := resolveValue(ref)
+		return value, "", err
+	}
+
+	keyName := strings.TrimPrefix(ref, wc_common.SecretRefKeyStore)
+	if err := wc_common.ValidateKeyName(keyName); err != nil {
+		return "", "", fmt.Errorf("%s: %w", ref, wc_common.ErrInvalidSecretRef)
+	}
+
+	keyStore, err := wc_common.GetKeyStore(ctx, config.KeyType)
+	if err != nil {
+		return "", "", err
+	}
+	exists, err := wc_common.HasKey(keyStore, keyName)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "", "", fmt.Errorf("%s: key not in the %s keystore: %w", ref, config.KeyType, wc_common.ErrKeyNotFound)
+	}
+	return "", keyStore.KeyPath(keyName), nil
+}
+
+// resolveValue reads an env or file reference. Surrounding whitespace is
+// trimmed, as secret files usually end with a newline
+func resolveValue(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, wc_common.SecretRefEnv):
+		name := strings.TrimPrefix(ref, wc_common.SecretRefEnv)
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			return "", fmt.Errorf("%s: environment variable is not set: %w", ref, wc_common.ErrInvalidSecretRef)
+		}
+		return strings.TrimSpace(value), nil
+	case strings.HasPrefix(ref, wc_common.SecretRefFile):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, wc_common.SecretRefFile))
+		if err != nil {
+			return "", fmt.Errorf("Error reading secret file: %w", err)
+		}
+		defer wc_common.WipeBytes(data)
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("%s: %w", ref, wc_common.ErrInvalidSecretRef)
+}
@@ -0,0 +1,527 @@
+package operator_config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// Problem is one finding of ValidateConfig. Line is 0 when the problem is
+// not tied to a field of the file
+type Problem struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Line == 0 && p.Field == "":
+		return p.Message
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.Field, p.Message)
+	default:
+		return fmt.Sprintf("%d: %s: %s", p.Line, p.Field, p.Message)
+	}
+}
+
+// configField is a field of the file with the line of its name and the
+// offset of its value
+type configField struct {
+	name   string
+	line   int
+	offset int64
+	value  json.RawMessage
+}
+
+type validator struct {
+	fields   map[string]configField
+	problems []Problem
+}
+
+// ValidateConfig checks a config file without using it: unknown fields,
+// values of the wrong type, conflicting operator keys, watchtower addresses
+// that don't match their keys and RPCs that are unreachable or on a chain
+// witnesschain is not deployed on. Fields of every profile are checked,
+// and the keys and RPCs of the base section with profile over it. Secret
+// references are resolved, and raw keys are a problem with strictSecrets.
+// Every problem found is returned, an empty result means the config is
+// valid
+func ValidateConfig(ctx context.Context, data []byte, profile string, strictSecrets bool) []Problem {
+	v := &validator{fields: map[string]configField{}}
+
+	config, ok := v.decode(data, profile)
+	if !ok {
+		return v.problems
+	}
+
+	v.checkKeySources(config)
+	if strictSecrets {
+		for _, field := range RawKeyFields(config) {
+			v.add(field, "is a raw private key, use an env:, file: or keystore: reference")
+		}
+	}
+	ResolveSecrets(ctx, config, func(field string, err error) {
+		v.add(field, "%v", err)
+	})
+	v.checkKeys(ctx, config)
+	v.checkRPCs(ctx, config)
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		return v.problems[i].Line != 0 && (v.problems[j].Line == 0 || v.problems[i].Line < v.problems[j].Line)
+	})
+	return v.problems
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	line := 0
+	if f, ok := v.fields[strings.SplitN(field, "[", 2)[0]]; ok {
+		line = f.line
+	}
+	v.problems = append(v.problems, Problem{Line: line, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) isSet(field string) bool {
+	_, ok := v.fields[field]
+	return ok
+}
+
+// decode reads the fields one by one, so that every unknown field and every
+// invalid value is reported and not only the first one. The fields of the
+// profile replace the fields of the base section
+func (v *validator) decode(data []byte, profile string) (*OperatorConfig, bool) {
+	fields, problem := readObject(data, 0, "")
+	if problem != nil {
+		v.problems = append(v.problems, *problem)
+		return nil, false
+	}
+	base := v.checkFields(fields, "")
+
+	profiles := map[string]map[string]configField{}
+	profilesField, hasProfiles := base[ProfilesField]
+	if hasProfiles {
+		delete(base, ProfilesField)
+
+		entries, problem := readObject(data, profilesField.offset, ProfilesField)
+		if problem != nil {
+			v.problems = append(v.problems, *problem)
+			return nil, false
+		}
+		for _, entry := range entries {
+			prefix := ProfilesField + "." + entry.name
+			profileFields, problem := readObject(data, entry.offset, prefix)
+			if problem != nil {
+				v.problems = append(v.problems, *problem)
+				continue
+			}
+			profiles[entry.name] = v.checkFields(profileFields, prefix+".")
+		}
+	}
+
+	v.fields = base
+	if profile != "" {
+		profileFields, ok := profiles[profile]
+		if !ok {
+			v.problems = append(v.problems, Problem{Line: profilesField.line, Field: ProfilesField, Message: fmt.Sprintf("profile %s not found", profile)})
+		}
+		for name, field := range profileFields {
+			v.fields[name] = field
+		}
+	}
+
+	// the values were checked by checkFields, invalid ones are left empty
+	config := &OperatorConfig{}
+	known := configFieldNames()
+	for name, field := range v.fields {
+		json.Unmarshal(field.value, reflect.ValueOf(config).Elem().Field(known[strings.ToLower(name)]).Addr().Interface())
+	}
+
+	SetDefaultValues(config)
+	return config, true
+}
+
+// checkFields reports unknown fields, duplicate fields and invalid values,
+// and returns the known fields by their json name. prefix is the path of
+// the object in the file, the base section has none
+func (v *validator) checkFields(fields []configField, prefix string) map[string]configField {
+	known := configFieldNames()
+	configType := reflect.TypeOf(OperatorConfig{})
+
+	result := map[string]configField{}
+	for _, field := range fields {
+		name := field.name
+		if prefix == "" && name == ProfilesField {
+			result[name] = field
+			continue
+		}
+
+		// encoding/json matches field names case insensitively
+		index, ok := known[strings.ToLower(name)]
+		if !ok {
+			message := "unknown field"
+			if suggestion := suggestField(name); suggestion != "" {
+				message = fmt.Sprintf("unknown field, did you mean %q", suggestion)
+			}
+			v.problems = append(v.problems, Problem{Line: field.line, Field: prefix + name, Message: message})
+			continue
+		}
+
+		tag := jsonName(configType.Field(index))
+		if first, ok := result[tag]; ok {
+			v.problems = append(v.problems, Problem{Line: field.line, Field: prefix + name, Message: fmt.Sprintf("duplicate field, first set on line %d", first.line)})
+			continue
+		}
+
+		fieldType := configType.Field(index).Type
+		if err := json.Unmarshal(field.value, reflect.New(fieldType).Interface()); err != nil {
+			v.problems = append(v.problems, Problem{Line: field.line, Field: prefix + name, Message: fmt.Sprintf("invalid value, expected %s", describeType(fieldType))})
+			continue
+		}
+		result[tag] = field
+	}
+	return result
+}
+
+// readObject reads the fields of the json object at offset of data. A
+// syntax error, or a value that is not an object, is returned as a problem
+// of field
+func readObject(data []byte, offset int64, field string) ([]configField, *Problem) {
+	decoder := json.NewDecoder(bytes.NewReader(data[offset:]))
+	problem := func(err error, message string) *Problem {
+		errOffset := decoder.InputOffset()
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) {
+			errOffset = syntaxError.Offset
+		}
+		if message == "" && err != nil {
+			message = err.Error()
+		}
+		if field == "" {
+			field = "json"
+		}
+		return &Problem{Line: lineOf(data, offset+errOffset), Field: field, Message: message}
+	}
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, problem(err, "must be a json object")
+	}
+
+	var fields []configField
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, problem(err, "")
+		}
+		line := lineOf(data, offset+decoder.InputOffset())
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, problem(err, "")
+		}
+		valueOffset := offset + decoder.InputOffset() - int64(len(value))
+
+		fields = append(fields, configField{name: token.(string), line: line, offset: valueOffset, value: value})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, problem(err, "")
+	}
+	return fields, nil
+}
+
+// checkKeySources reports an operator that has both a raw key and an
+// encrypted key, or no key nor address. Watchtower keys of every kind are
+// used together, as the config is loaded
+func (v *validator) checkKeySources(config *OperatorConfig) {
+	if v.isSet("operator_private_key") && v.isSet("operator_encrypted_key") {
+		v.add("operator_encrypted_key", "conflicts with operator_private_key, set only one operator key")
+	}
+	if !v.isSet("watchtower_hd_indexes") {
+		for _, field := range []string{"hd_seed", "hd_derivation_path"} {
+			if v.isSet(field) {
+				v.add(field, "is only used with watchtower_hd_indexes")
+			}
+		}
+	} else if _, err := wc_common.HDPathForIndex(config.HDDerivationPath, 0); err != nil {
+		v.add("hd_derivation_path", "%v", err)
+	}
+
+	if config.OperatorPrivateKeyHex == "" && config.OperatorEncryptedKey == "" && config.OperatorAddress == (common.Address{}) {
+		v.add("operator_address", "no operator key or address is set")
+	}
+	if config.EthRPCUrl == "" {
+		v.add("eth_rpc_url", "is required")
+	}
+}
+
+// checkKeys reads the address of every key, from the key metadata when it
+// is recorded, and compares it with the configured addresses
+func (v *validator) checkKeys(ctx context.Context, config *OperatorConfig) {
+	var addresses []common.Address
+	var fields []string
+	// complete is false when a watchtower key could not be read, its
+	// address is then missing from addresses
+	complete := true
+
+	for i, hexKey := range config.WatchtowerPrivateKeysHex {
+		field := fmt.Sprintf("watchtower_private_keys[%d]", i)
+		key, err := crypto.HexToECDSA(hexKey)
+		if err != nil {
+			v.add(field, "is not a valid private key")
+			complete = false
+			continue
+		}
+		addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+		fields = append(fields, field)
+		wc_common.WipePrivateKey(key)
+	}
+
+	var keyStore wc_common.KeyStore
+	if v.isSet("encrypted_key_type") || len(config.WatchtowerEncryptedKeys) != 0 || config.OperatorEncryptedKey != "" {
+		var err error
+		if keyStore, err = wc_common.GetKeyStore(ctx, config.KeyType); err != nil {
+			v.add("encrypted_key_type", "%s is not one of %s", config.KeyType, strings.Join(wc_common.GetKeyStoreTypes(), "/"))
+		}
+	}
+
+	if keyStore != nil && len(config.WatchtowerEncryptedKeys) != 0 {
+		wc_common.RetryMounting()
+		keyStore.UseKeyPath(config.WatchtowerEncryptedKeys[0])
+		wc_common.UseEncryptedKeys(config.KeyType)
+	}
+
+	for i, keyName := range config.WatchtowerEncryptedKeys {
+		if keyStore == nil {
+			complete = false
+			break
+		}
+		field := fmt.Sprintf("watchtower_encrypted_keys[%d]", i)
+		address, err := wc_common.GetKeyAddress(keyStore, config.KeyType, keyName)
+		if err != nil {
+			v.add(field, "%s: %v", keyName, err)
+			complete = false
+			continue
+		}
+		addresses = append(addresses, address)
+		fields = append(fields, field)
+	}
+
+	// the seed and the indexes are checked whether or not
+	// watchtower_addresses is set, the derivation path is checked with the
+	// key sources
+	if len(config.WatchtowerHDIndexes) != 0 {
+		seed, err := wc_common.LoadSeed(ctx, config.HDSeedName)
+		if err != nil {
+			v.add("hd_seed", "%v", err)
+			complete = false
+		}
+		derive := seed != nil
+		if _, err := wc_common.HDPathForIndex(config.HDDerivationPath, 0); err != nil {
+			derive, complete = false, false
+		}
+		for i, index := range config.WatchtowerHDIndexes {
+			field := fmt.Sprintf("watchtower_hd_indexes[%d]", i)
+			if index >= wc_common.HDHardenedKeyStart {
+				v.add(field, "%d: %v", index, wc_common.ErrInvalidHDIndex)
+				complete = false
+				continue
+			}
+			if !derive {
+				continue
+			}
+			key, err := wc_common.DeriveKeyFromSeed(seed, config.HDDerivationPath, index)
+			if err != nil {
+				v.add(field, "%v", err)
+				complete = false
+				continue
+			}
+			addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+			fields = append(fields, field)
+			wc_common.WipePrivateKey(key)
+		}
+		wc_common.WipeBytes(seed)
+	}
+
+	// watchtower_addresses alone is used to deregister watchtowers, set
+	// together with keys it must list the addresses of the keys in order
+	// or loading the config fails. The addresses are only compared when
+	// every key was read, the keys that were not are already reported
+	if len(config.WatchtowerAddresses) != 0 && len(addresses) != 0 && complete {
+		if len(config.WatchtowerAddresses) != len(addresses) {
+			v.add("watchtower_addresses", "has %d addresses but the config has %d watchtower keys", len(config.WatchtowerAddresses), len(addresses))
+		}
+		for i := 0; i < len(config.WatchtowerAddresses) && i < len(addresses); i++ {
+			if config.WatchtowerAddresses[i] != addresses[i] {
+				v.add(fmt.Sprintf("watchtower_addresses[%d]", i), "is %s but the key of %s is %s", config.WatchtowerAddresses[i].Hex(), fields[i], addresses[i].Hex())
+			}
+		}
+	}
+
+	var operatorAddress common.Address
+	var operatorField string
+	if config.OperatorPrivateKeyHex != "" {
+		key, err := crypto.HexToECDSA(config.OperatorPrivateKeyHex)
+		if err != nil {
+			v.add("operator_private_key", "is not a valid private key")
+		} else {
+			operatorAddress, operatorField = crypto.PubkeyToAddress(key.PublicKey), "operator_private_key"
+			wc_common.WipePrivateKey(key)
+		}
+	}
+	if config.OperatorEncryptedKey != "" && keyStore != nil {
+		address, err := wc_common.GetKeyAddress(keyStore, config.KeyType, config.OperatorEncryptedKey)
+		if err != nil {
+			v.add("operator_encrypted_key", "%s: %v", config.OperatorEncryptedKey, err)
+		} else if operatorField == "" {
+			operatorAddress, operatorField = address, "operator_encrypted_key"
+		}
+	}
+	if operatorField != "" && config.OperatorAddress != (common.Address{}) && config.OperatorAddress != operatorAddress {
+		v.add("operator_address", "is %s but the key of %s is %s", config.OperatorAddress.Hex(), operatorField, operatorAddress.Hex())
+	}
+}
+
+// checkRPCs connects to every RPC of the config and checks that
+// witnesschain is deployed on its chain
+func (v *validator) checkRPCs(ctx context.Context, config *OperatorConfig) {
+	rpcs := []struct {
+		field string
+		url   string
+	}{
+		{"eth_rpc_url", config.EthRPCUrl},
+		{"proof_submission_rpc_urL", config.ProofSubmissionRPC},
+	}
+
+	for _, rpc := range rpcs {
+		if rpc.url == "" {
+			continue
+		}
+
+		chainID, err := getChainID(ctx, rpc.url)
+		if err != nil {
+			v.add(rpc.field, "%s is not reachable: %v", HideUrl(rpc.url), err)
+			continue
+		}
+		if _, err := wc_common.GetChainConfig(chainID); err != nil {
+			v.add(rpc.field, "%v", err)
+		}
+	}
+}
+
+func getChainID(ctx context.Context, url string) (*big.Int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return client.ChainID(ctx)
+}
+
+// configFieldNames maps the lower case json name of every config field to
+// its index, fields without a json tag are not read from the file
+func configFieldNames() map[string]int {
+	names := map[string]int{}
+	configType := reflect.TypeOf(OperatorConfig{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := jsonName(configType.Field(i)); name != "" {
+			names[strings.ToLower(name)] = i
+		}
+	}
+	return names
+}
+
+func jsonName(field reflect.StructField) string {
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// suggestField returns the known field closest to an unknown one, like
+// expiry_in_days for expiry. Fields the name is a prefix of, or that are a
+// prefix of it, come first, then the lowest edit distance and then the
+// name order
+func suggestField(name string) string {
+	name = strings.ToLower(name)
+	var knownNames []string
+	for known := range configFieldNames() {
+		knownNames = append(knownNames, known)
+	}
+	sort.Strings(knownNames)
+
+	best, bestPrefix, bestDistance := "", false, 3
+	for _, known := range knownNames {
+		prefix := strings.HasPrefix(known, name) || strings.HasPrefix(name, known)
+		distance := editDistance(name, known)
+		switch {
+		case prefix && (!bestPrefix || distance < bestDistance):
+			best, bestPrefix, bestDistance = known, true, distance
+		case !prefix && !bestPrefix && distance < bestDistance:
+			best, bestDistance = known, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return tagOf(best)
+}
+
+func tagOf(lowerName string) string {
+	return jsonName(reflect.TypeOf(OperatorConfig{}).Field(configFieldNames()[lowerName]))
+}
+
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func describeType(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeOf(common.Address{}):
+		return "an address"
+	case t.Kind() == reflect.Slice && t.Elem() == reflect.TypeOf(common.Address{}):
+		return "a list of addresses"
+	case t.Kind() == reflect.Slice:
+		return "a list of " + t.Elem().Kind().String()
+	case t.Kind() == reflect.Uint64 || t.Kind() == reflect.Uint32:
+		return "a positive number"
+	default:
+		return "a " + t.Kind().String()
+	}
+}
+
+func lineOf(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
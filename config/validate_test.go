@@ -0,0 +1,103 @@
+package operator_config
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+func TestValidateWatchtowerAddresses(t *testing.T) {
+	var keys []string
+	var addresses []common.Address
+	for i := 0; i < 2; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, common.Bytes2Hex(crypto.FromECDSA(key)))
+		addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	other := common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+	tests := []struct {
+		name      string
+		addresses []common.Address
+		// fields are the watchtower fields of the expected problems
+		fields []string
+	}{
+		{"keys only", nil, nil},
+		{"same order", addresses, nil},
+		{"other order", []common.Address{addresses[1], addresses[0]}, []string{"watchtower_addresses[0]", "watchtower_addresses[1]"}},
+		{"fewer", addresses[:1], []string{"watchtower_addresses"}},
+		{"more", []common.Address{addresses[0], addresses[1], other}, []string{"watchtower_addresses"}},
+		{"other address", []common.Address{addresses[0], other}, []string{"watchtower_addresses[1]"}},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(map[string]interface{}{
+			"operator_address":        "0x0000000000000000000000000000000000000001",
+			"watchtower_addresses":    test.addresses,
+			"watchtower_private_keys": keys,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var fields []string
+		for _, problem := range ValidateConfig(context.Background(), data, "", false) {
+			if strings.HasPrefix(problem.Field, "watchtower_") {
+				fields = append(fields, problem.Field)
+			}
+		}
+		if strings.Join(fields, ",") != strings.Join(test.fields, ",") {
+			t.Errorf("%s: problems of %v, want %v", test.name, fields, test.fields)
+		}
+	}
+}
+
+func TestSuggestField(t *testing.T) {
+	tests := map[string]string{
+		"expiry":              "expiry_in_days",
+		"watchtower":          "watchtower_addresses",
+		"watchtower_adresses": "watchtower_addresses",
+		"gas_limt":            "gas_limit",
+		"Operator_Adress":     "operator_address",
+		"unrelated_option":    "",
+	}
+	for name, want := range tests {
+		// the suggestion is the same on every run, whatever the map order
+		for i := 0; i < 20; i++ {
+			if got := suggestField(name); got != want {
+				t.Fatalf("suggestField(%s) = %s, want %s", name, got, want)
+			}
+		}
+	}
+}
+
+func TestValidateHDIndexes(t *testing.T) {
+	// the seed and the indexes are checked without watchtower_addresses
+	data, err := json.Marshal(map[string]interface{}{
+		"operator_address":      "0x0000000000000000000000000000000000000001",
+		"watchtower_hd_indexes": []uint32{0, wc_common.HDHardenedKeyStart},
+		"hd_seed":               "missing",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields []string
+	for _, problem := range ValidateConfig(context.Background(), data, "", false) {
+		if strings.HasPrefix(problem.Field, "watchtower_") || strings.HasPrefix(problem.Field, "hd_") {
+			fields = append(fields, problem.Field)
+		}
+	}
+	want := []string{"hd_seed", "watchtower_hd_indexes[1]"}
+	if strings.Join(fields, ",") != strings.Join(want, ",") {
+		t.Errorf("problems of %v, want %v", fields, want)
+	}
+}
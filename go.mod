@@ -6,9 +6,12 @@ require (
 	github.com/Layr-Labs/eigensdk-go v0.1.8
 	github.com/ethereum/go-ethereum v1.14.5
 	github.com/howeyc/gopass v0.0.0-20210920133722-c8aef6fb66ef
+	github.com/miekg/pkcs11 v1.1.2
+	github.com/tyler-smith/go-bip39 v1.1.0
 	github.com/urfave/cli/v2 v2.27.2
 	github.com/wagslane/go-password-validator v0.3.0
 	github.com/witnesschain-com/diligencewatchtower-client v1.0.8
+	golang.org/x/crypto v0.24.0
 )
 
 require (
@@ -35,7 +38,6 @@ require (
 	github.com/tklauser/go-sysconf v0.3.12 // indirect
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/xrash/smetrics v0.0.0-20240312152122-5f08fbb34913 // indirect
-	golang.org/x/crypto v0.24.0 // indirect
 	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.21.0 // indirect
@@ -0,0 +1,128 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	"github.com/witnesschain-com/operator-cli/common/bindings/AvsDirectory"
+	"github.com/witnesschain-com/operator-cli/common/bindings/WitnessHub"
+)
+
+// RegisterOperatorToAVS registers the operator of the client to the
+// WitnessHub AVS, an operator already registered returns
+// ErrAlreadyRegistered
+func (c *Client) RegisterOperatorToAVS(ctx context.Context) (*TxResult, error) {
+	avsDirectory, witnessHub, err := c.avsContracts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := c.IsOperatorRegistered(ctx, c.OperatorAddress())
+	if err != nil {
+		return nil, err
+	}
+	if registered {
+		return nil, fmt.Errorf("Operator %s: %w", c.OperatorAddress().Hex(), wc_common.ErrAlreadyRegistered)
+	}
+
+	expiry, err := c.calculateExpiry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operatorSignature, err := c.operatorSignature(ctx, avsDirectory, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	transactOpts, err := c.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := witnessHub.RegisterOperatorToAVS(transactOpts, c.OperatorAddress(), operatorSignature)
+	if err != nil {
+		return nil, fmt.Errorf("Registering operator to AVS failed: %w", err)
+	}
+
+	result, err := c.waitMined(ctx, tx)
+	return &result, err
+}
+
+// DeRegisterOperatorFromAVS deregisters the operator of the client from the
+// WitnessHub AVS, an operator that is not registered returns
+// ErrNotRegistered
+func (c *Client) DeRegisterOperatorFromAVS(ctx context.Context) (*TxResult, error) {
+	_, witnessHub, err := c.avsContracts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	registered, err := c.IsOperatorRegistered(ctx, c.OperatorAddress())
+	if err != nil {
+		return nil, err
+	}
+	if !registered {
+		return nil, fmt.Errorf("Operator %s: %w", c.OperatorAddress().Hex(), wc_common.ErrNotRegistered)
+	}
+
+	transactOpts, err := c.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := witnessHub.DeregisterOperatorFromAVS(transactOpts, c.OperatorAddress())
+	if err != nil {
+		return nil, fmt.Errorf("deregistering operator to AVS failed: %w", err)
+	}
+
+	result, err := c.waitMined(ctx, tx)
+	return &result, err
+}
+
+// avsContracts checks that the operator is whitelisted and returns the AVS
+// contracts of the chain
+func (c *Client) avsContracts(ctx context.Context) (*AvsDirectory.AvsDirectory, *WitnessHub.WitnessHub, error) {
+	avsDirectory, err := c.avsDirectory()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.requireWhitelisted(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	witnessHub, err := WitnessHub.NewWitnessHub(c.chain.WitnessHubAddress, c.client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Instantiating WitnessHub contract failed: %w", err)
+	}
+	return avsDirectory, witnessHub, nil
+}
+
+func (c *Client) operatorSignature(ctx context.Context, avsDirectory *AvsDirectory.AvsDirectory, expiry *big.Int) (WitnessHub.ISignatureUtilsSignatureWithSaltAndExpiry, error) {
+	var operatorSignature WitnessHub.ISignatureUtilsSignatureWithSaltAndExpiry
+
+	salt, err := wc_common.GenerateSalt()
+	if err != nil {
+		return operatorSignature, err
+	}
+
+	digestHash, err := avsDirectory.CalculateOperatorAVSRegistrationDigestHash(&bind.CallOpts{Context: ctx}, c.OperatorAddress(), c.chain.WitnessHubAddress, salt, expiry)
+	if err != nil {
+		return operatorSignature, fmt.Errorf("Digest hash calculation failed: %w", err)
+	}
+
+	signature, err := c.config.Operator.SignDigest(ctx, c.chainID, digestHash[:])
+	if err != nil {
+		return operatorSignature, err
+	}
+
+	operatorSignature = WitnessHub.ISignatureUtilsSignatureWithSaltAndExpiry{
+		Signature: signature,
+		Salt:      salt,
+		Expiry:    expiry,
+	}
+	return operatorSignature, nil
+}
@@ -0,0 +1,256 @@
+// Package operator registers an operator and its watchtowers with the
+// witnesschain contracts. It is the library behind the operator cli
+// commands: nothing is printed and every method returns its result
+package operator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	"github.com/witnesschain-com/operator-cli/common/bindings/AvsDirectory"
+	"github.com/witnesschain-com/operator-cli/common/bindings/OperatorRegistry"
+)
+
+// Config is what a Client needs to send transactions on one chain
+type Config struct {
+	RPCUrl string
+	// Chain overrides the contract addresses known for the chain id of the
+	// RPC, for devnets and forks
+	Chain *wc_common.ChainConfig
+	// Operator signs every transaction
+	Operator         Signer
+	ExpiryInDays     uint64
+	TxReceiptTimeout time.Duration
+	// OnTxSent is called once a transaction is sent, before its receipt is
+	// awaited
+	OnTxSent func(tx TxResult)
+}
+
+// TxResult is a transaction sent by the client, Receipt is set once it is
+// mined
+type TxResult struct {
+	Hash    common.Hash
+	URL     string
+	Receipt *types.Receipt
+}
+
+// Client is connected to one chain, it is safe to use from one goroutine at
+// a time since the nonce of the operator is shared
+type Client struct {
+	config           Config
+	client           *ethclient.Client
+	chainID          *big.Int
+	chain            wc_common.ChainConfig
+	operatorRegistry *OperatorRegistry.OperatorRegistry
+}
+
+// NewClient connects to the RPC of config, the chain must be known in
+// the networks, built-in or of the networks file, unless config.Chain is set
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	if config.Operator == nil {
+		return nil, wc_common.ErrZeroOperatorAddress
+	}
+	if config.ExpiryInDays == 0 {
+		config.ExpiryInDays = wc_common.DefaultExpiration
+	}
+	if config.TxReceiptTimeout == 0 {
+		config.TxReceiptTimeout = time.Duration(wc_common.DefaultTxReceiptTimeout) * time.Second
+	}
+
+	client, err := ethclient.DialContext(ctx, config.RPCUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Connection to RPC failed: %w", err)
+	}
+
+	c := &Client{config: config, client: client}
+	if err := c.init(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewReadOnlyClient connects to rpcUrl to read registrations by address,
+// it has no operator and can't send transactions. chain is nil for a
+// known network
+func NewReadOnlyClient(ctx context.Context, rpcUrl string, chain *wc_common.ChainConfig) (*Client, error) {
+	client, err := ethclient.DialContext(ctx, rpcUrl)
+	if err != nil {
+		return nil, fmt.Errorf("Connection to RPC failed: %w", err)
+	}
+
+	c := &Client{config: Config{RPCUrl: rpcUrl, Chain: chain}, client: client}
+	if err := c.init(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) init(ctx context.Context) error {
+	var err error
+	c.chainID, err = c.client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to retrive chainID for %s: %w", c.config.RPCUrl, err)
+	}
+
+	if c.config.Chain != nil {
+		c.chain = *c.config.Chain
+	} else if c.chain, err = wc_common.GetChainConfig(c.chainID); err != nil {
+		return err
+	}
+
+	c.operatorRegistry, err = OperatorRegistry.NewOperatorRegistry(c.chain.OperatorRegistryAddress, c.client)
+	if err != nil {
+		return fmt.Errorf("Instantiating OperatorRegistry contract failed: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) Close() {
+	c.client.Close()
+}
+
+func (c *Client) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
+// Chain returns the contract addresses the client uses
+func (c *Client) Chain() wc_common.ChainConfig {
+	return c.chain
+}
+
+// OperatorAddress is the zero address for a read only client
+func (c *Client) OperatorAddress() common.Address {
+	if c.config.Operator == nil {
+		return common.Address{}
+	}
+	return c.config.Operator.Address()
+}
+
+func (c *Client) IsOperatorWhitelisted(ctx context.Context, operator common.Address) (bool, error) {
+	whitelisted, err := c.operatorRegistry.IsActiveOperator(&bind.CallOpts{Context: ctx}, operator)
+	if err != nil {
+		return false, fmt.Errorf("Error checking if operator is whitelisted: %w", err)
+	}
+	return whitelisted, nil
+}
+
+func (c *Client) IsWatchtowerRegistered(ctx context.Context, watchtower common.Address) (bool, error) {
+	registered, err := c.operatorRegistry.IsValidWatchtower(&bind.CallOpts{Context: ctx}, watchtower)
+	if err != nil {
+		return false, fmt.Errorf("Error checking if watchtower is already registered: %w", err)
+	}
+	return registered, nil
+}
+
+// WatchtowerOperator returns the operator a registered watchtower belongs to
+func (c *Client) WatchtowerOperator(ctx context.Context, watchtower common.Address) (common.Address, error) {
+	operator, err := c.operatorRegistry.GetOperator(&bind.CallOpts{Context: ctx}, watchtower)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("Error getting the operator of watchtower %s: %w", watchtower.Hex(), err)
+	}
+	return operator, nil
+}
+
+// HasAVS reports whether the chain of the client has the WitnessHub AVS
+func (c *Client) HasAVS() bool {
+	return c.chain.WitnessHubAddress != (common.Address{})
+}
+
+// IsOperatorRegistered reports whether operator is registered to the AVS,
+// only chains with a WitnessHub have one
+func (c *Client) IsOperatorRegistered(ctx context.Context, operator common.Address) (bool, error) {
+	avsDirectory, err := c.avsDirectory()
+	if err != nil {
+		return false, err
+	}
+
+	status, err := avsDirectory.AvsOperatorStatus(&bind.CallOpts{Context: ctx}, c.chain.WitnessHubAddress, operator)
+	if err != nil {
+		return false, fmt.Errorf("Checking operator status failed: %w", err)
+	}
+	return status != 0, nil
+}
+
+func (c *Client) avsDirectory() (*AvsDirectory.AvsDirectory, error) {
+	if c.chain.WitnessHubAddress.Cmp(common.Address{0}) == 0 {
+		return nil, fmt.Errorf("WitnessHub not found at %v: %w", c.config.RPCUrl, wc_common.ErrWrongChain)
+	}
+
+	avsDirectory, err := AvsDirectory.NewAvsDirectory(c.chain.AVSDirectoryAddress, c.client)
+	if err != nil {
+		return nil, fmt.Errorf("Instantiating AvsDirectory contract failed: %w", err)
+	}
+	return avsDirectory, nil
+}
+
+// requireWhitelisted fails with ErrNotWhitelisted when the operator of the
+// client can't register anything, and with ErrZeroOperatorAddress for a
+// read only client. Every transaction goes through it
+func (c *Client) requireWhitelisted(ctx context.Context) error {
+	if c.config.Operator == nil {
+		return wc_common.ErrZeroOperatorAddress
+	}
+
+	whitelisted, err := c.IsOperatorWhitelisted(ctx, c.OperatorAddress())
+	if err != nil {
+		return err
+	}
+	if !whitelisted {
+		return fmt.Errorf("Operator %s: %w", c.OperatorAddress().Hex(), wc_common.ErrNotWhitelisted)
+	}
+	return nil
+}
+
+func (c *Client) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	transactOpts, err := c.config.Operator.TransactOpts(ctx, c.chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.chain.GasPrice == -1 {
+		transactOpts.GasPrice = big.NewInt(0)
+	}
+	return transactOpts, nil
+}
+
+func (c *Client) calculateExpiry(ctx context.Context) (*big.Int, error) {
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Could not get HeaderByNumber: %w", err)
+	}
+
+	expiryInSeconds := int64(c.config.ExpiryInDays * 24 * 60 * 60)
+	return new(big.Int).Add(new(big.Int).SetUint64(header.Time), big.NewInt(expiryInSeconds)), nil
+}
+
+// waitMined reports tx as sent and waits for its receipt, a reverted
+// transaction returns ErrTransactionFailed along with its receipt
+func (c *Client) waitMined(ctx context.Context, tx *types.Transaction) (TxResult, error) {
+	result := TxResult{Hash: tx.Hash(), URL: fmt.Sprintf("%s/tx/%s", c.chain.BlockExplorer, tx.Hash().Hex())}
+	if c.config.OnTxSent != nil {
+		c.config.OnTxSent(result)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.TxReceiptTimeout)
+	defer cancel()
+
+	receipt, err := bind.WaitMined(ctx, c.client, tx)
+	if err != nil {
+		return result, fmt.Errorf("Transaction failed: %w", err)
+	}
+	result.Receipt = receipt
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return result, fmt.Errorf("tx %s: %w", tx.Hash().Hex(), wc_common.ErrTransactionFailed)
+	}
+	return result, nil
+}
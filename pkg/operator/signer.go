@@ -0,0 +1,143 @@
+package operator
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/witnesschain-com/diligencewatchtower-client/keystore"
+)
+
+// Signer signs the registration digests and the transactions of one
+// address. The chain id is given on every call, so one signer can be used
+// on L1 and on the proof submission chain
+type Signer interface {
+	Address() common.Address
+	SignDigest(ctx context.Context, chainID *big.Int, digest []byte) ([]byte, error)
+	TransactOpts(ctx context.Context, chainID *big.Int) (*bind.TransactOpts, error)
+}
+
+// VaultSigner signs with a local private key or through the external signer
+// endpoint, using the vault of the watchtower client
+type VaultSigner struct {
+	address    common.Address
+	privateKey *ecdsa.PrivateKey
+	endpoint   string
+
+	lock   sync.Mutex
+	vaults map[string]*keystore.Vault
+}
+
+// NewKeySigner returns a signer holding privateKey in memory
+func NewKeySigner(privateKey *ecdsa.PrivateKey) *VaultSigner {
+	return NewVaultSigner(crypto.PubkeyToAddress(privateKey.PublicKey), privateKey, "")
+}
+
+// NewExternalSigner returns a signer that sends every signing request for
+// address to endpoint
+func NewExternalSigner(address common.Address, endpoint string) *VaultSigner {
+	return NewVaultSigner(address, nil, endpoint)
+}
+
+// NewVaultSigner returns a signer with the fields of keystore.VaultConfig,
+// privateKey is used when set and endpoint otherwise
+func NewVaultSigner(address common.Address, privateKey *ecdsa.PrivateKey, endpoint string) *VaultSigner {
+	return &VaultSigner{address: address, privateKey: privateKey, endpoint: endpoint, vaults: map[string]*keystore.Vault{}}
+}
+
+func (s *VaultSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *VaultSigner) SignDigest(ctx context.Context, chainID *big.Int, digest []byte) ([]byte, error) {
+	vault, err := s.vault(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := vault.SignData(digest, apitypes.DataTyped.Mime)
+	if err != nil {
+		return nil, fmt.Errorf("Signing the digest hash failed: %w", err)
+	}
+	return signature, nil
+}
+
+func (s *VaultSigner) TransactOpts(ctx context.Context, chainID *big.Int) (*bind.TransactOpts, error) {
+	vault, err := s.vault(chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactOpts := vault.NewTransactOpts(chainID)
+	transactOpts.Context = ctx
+	return transactOpts, nil
+}
+
+func (s *VaultSigner) vault(chainID *big.Int) (*keystore.Vault, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if vault, ok := s.vaults[chainID.String()]; ok {
+		return vault, nil
+	}
+
+	vc := &keystore.VaultConfig{Address: s.address, ChainID: chainID, PrivateKey: s.privateKey, Endpoint: s.endpoint}
+	vault, err := keystore.SetupVault(vc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to setup vault %s: %w", s.address.Hex(), err)
+	}
+	s.vaults[chainID.String()] = vault
+	return vault, nil
+}
+
+// HashSigner signs with a function that signs 32 byte hashes, for keys that
+// never leave a token or a remote service. signHash returns [R || S || V]
+// with V as 0/1, like crypto.Sign
+type HashSigner struct {
+	address  common.Address
+	signHash func(hash []byte) ([]byte, error)
+}
+
+func NewHashSigner(address common.Address, signHash func(hash []byte) ([]byte, error)) *HashSigner {
+	return &HashSigner{address: address, signHash: signHash}
+}
+
+func (s *HashSigner) Address() common.Address {
+	return s.address
+}
+
+// SignDigest returns V as 27/28, as the registration signatures are
+// checked with ecrecover
+func (s *HashSigner) SignDigest(ctx context.Context, chainID *big.Int, digest []byte) ([]byte, error) {
+	signature, err := s.signHash(digest)
+	if err != nil {
+		return nil, fmt.Errorf("Signing the digest hash failed: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+func (s *HashSigner) TransactOpts(ctx context.Context, chainID *big.Int) (*bind.TransactOpts, error) {
+	txSigner := types.LatestSignerForChainID(chainID)
+	return &bind.TransactOpts{
+		From:    s.address,
+		Context: ctx,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != s.address {
+				return nil, bind.ErrNotAuthorized
+			}
+			signature, err := s.signHash(txSigner.Hash(tx).Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("Signing the transaction failed: %w", err)
+			}
+			return tx.WithSignature(txSigner, signature)
+		},
+	}, nil
+}
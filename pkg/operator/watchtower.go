@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// WatchtowerTx is the registration or deregistration of one watchtower
+type WatchtowerTx struct {
+	Watchtower common.Address
+	TxResult
+}
+
+// WatchtowerResult lists the watchtowers that were sent a transaction and
+// the ones skipped because they were already in the requested state
+type WatchtowerResult struct {
+	ChainID      *big.Int
+	Transactions []WatchtowerTx
+	Skipped      []common.Address
+}
+
+// RegisterWatchtowers registers every watchtower to the operator of the
+// client, each watchtower signs the address of the operator. On error the
+// result holds the watchtowers done so far
+func (c *Client) RegisterWatchtowers(ctx context.Context, watchtowers []Signer) (*WatchtowerResult, error) {
+	result := &WatchtowerResult{ChainID: c.ChainID()}
+
+	if err := c.requireWhitelisted(ctx); err != nil {
+		return result, err
+	}
+
+	transactOpts, err := c.transactOpts(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	expiry, err := c.calculateExpiry(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, watchtower := range watchtowers {
+		registered, err := c.IsWatchtowerRegistered(ctx, watchtower.Address())
+		if err != nil {
+			return result, err
+		}
+		if registered {
+			result.Skipped = append(result.Skipped, watchtower.Address())
+			continue
+		}
+
+		salt, err := wc_common.GenerateSalt()
+		if err != nil {
+			return result, err
+		}
+		signedMessage, err := c.signOperatorAddress(ctx, watchtower, salt, expiry)
+		if err != nil {
+			return result, err
+		}
+
+		tx, err := c.operatorRegistry.RegisterWatchtowerAsOperator(transactOpts, watchtower.Address(), salt, expiry, signedMessage)
+		if err != nil {
+			return result, fmt.Errorf("Registering watchtower as operator failed: %w", err)
+		}
+
+		txResult, err := c.waitMined(ctx, tx)
+		result.Transactions = append(result.Transactions, WatchtowerTx{Watchtower: watchtower.Address(), TxResult: txResult})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// DeRegisterWatchtowers deregisters every watchtower, only the operator
+// signs so no watchtower key is needed
+func (c *Client) DeRegisterWatchtowers(ctx context.Context, watchtowers []common.Address) (*WatchtowerResult, error) {
+	result := &WatchtowerResult{ChainID: c.ChainID()}
+
+	if err := c.requireWhitelisted(ctx); err != nil {
+		return result, err
+	}
+
+	transactOpts, err := c.transactOpts(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, watchtower := range watchtowers {
+		registered, err := c.IsWatchtowerRegistered(ctx, watchtower)
+		if err != nil {
+			return result, err
+		}
+		if !registered {
+			result.Skipped = append(result.Skipped, watchtower)
+			continue
+		}
+
+		tx, err := c.operatorRegistry.DeRegister(transactOpts, watchtower)
+		if err != nil {
+			return result, fmt.Errorf("Deregistering watchtower failed: %w", err)
+		}
+
+		txResult, err := c.waitMined(ctx, tx)
+		result.Transactions = append(result.Transactions, WatchtowerTx{Watchtower: watchtower, TxResult: txResult})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) signOperatorAddress(ctx context.Context, watchtower Signer, salt [32]byte, expiry *big.Int) ([]byte, error) {
+	digestHash, err := c.operatorRegistry.CalculateWatchtowerRegistrationMessageHash(&bind.CallOpts{Context: ctx}, c.OperatorAddress(), salt, expiry)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate digest hash: %w", err)
+	}
+
+	signature, err := watchtower.SignDigest(ctx, c.chainID, digestHash[:])
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign operator address: %w", err)
+	}
+	return signature, nil
+}
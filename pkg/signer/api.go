@@ -0,0 +1,167 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// ExternalAPIVersion is the version of the clef external api the signer
+// implements
+const ExternalAPIVersion = "6.1.0"
+
+type remoteAddrKey struct{}
+
+// accountAPI is the account_ namespace of clef
+type accountAPI struct {
+	server *Server
+}
+
+// signTransactionResult is the result of account_signTransaction in clef
+type signTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func (api *accountAPI) Version() string {
+	return ExternalAPIVersion
+}
+
+func (api *accountAPI) List() []common.Address {
+	return api.server.Addresses()
+}
+
+// SignData signs data/typed data, EIP-712 typed data of a registration
+// contract or a raw digest the allowlist allows. Personal messages are
+// refused, they could be a transaction or a permit that the allowlist of
+// the key can't check
+func (api *accountAPI) SignData(ctx context.Context, contentType string, address common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.server.signData(ctx, "account_signData", address.Address(), contentType, data)
+}
+
+func (api *accountAPI) SignTypedData(ctx context.Context, address common.MixedcaseAddress, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	return api.server.signTypedData(ctx, "account_signTypedData", address.Address(), typedData)
+}
+
+func (api *accountAPI) SignTransaction(ctx context.Context, args apitypes.SendTxArgs) (*signTransactionResult, error) {
+	tx, err := api.server.signTransaction(ctx, "account_signTransaction", args)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding transaction: %w", err)
+	}
+	return &signTransactionResult{Raw: raw, Tx: tx}, nil
+}
+
+// ethAPI is the eth_ namespace served by Web3Signer
+type ethAPI struct {
+	server *Server
+}
+
+func (api *ethAPI) Accounts() []common.Address {
+	return api.server.Addresses()
+}
+
+// Sign is refused, as the allowlist can't check a personal message. It is
+// still audited
+func (api *ethAPI) Sign(ctx context.Context, address common.Address, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.server.signData(ctx, "eth_sign", address, accounts.MimetypeTextPlain, data)
+}
+
+func (api *ethAPI) SignTypedData(ctx context.Context, address common.Address, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	return api.server.signTypedData(ctx, "eth_signTypedData", address, typedData)
+}
+
+func (api *ethAPI) SignTransaction(ctx context.Context, args apitypes.SendTxArgs) (hexutil.Bytes, error) {
+	tx, err := api.server.signTransaction(ctx, "eth_signTransaction", args)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding transaction: %w", err)
+	}
+	return raw, nil
+}
+
+func (s *Server) handleUpcheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, "OK")
+}
+
+// handlePublicKeys lists the uncompressed public keys without the 0x04
+// prefix, as Web3Signer does
+func (s *Server) handlePublicKeys(w http.ResponseWriter, r *http.Request) {
+	publicKeys := []string{}
+	for _, address := range s.address {
+		publicKeys = append(publicKeys, publicKeyHex(s.keys[address]))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publicKeys)
+}
+
+// handleSign signs the keccak256 hash of data as Web3Signer does, data must
+// be an encoded EIP-712 message of a registration contract. The
+// identifier is the public key or the address of the key
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Data hexutil.Bytes `json:"data"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("Error reading request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	address, ok := s.identifierAddress(r.PathValue("identifier"))
+	if !ok {
+		http.Error(w, wc_common.ErrUnknownSigningKey.Error(), http.StatusNotFound)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), remoteAddrKey{}, r.RemoteAddr)
+	signature, err := s.signMessage(ctx, "eth1_sign", address, request.Data)
+	switch {
+	case errors.Is(err, wc_common.ErrUnknownSigningKey):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, wc_common.ErrSigningDenied):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, hexutil.Encode(signature))
+	}
+}
+
+func (s *Server) identifierAddress(identifier string) (common.Address, bool) {
+	if common.IsHexAddress(identifier) {
+		return common.HexToAddress(identifier), true
+	}
+
+	for address, key := range s.keys {
+		if strings.EqualFold(identifier, publicKeyHex(key)) {
+			return address, true
+		}
+	}
+	return common.Address{}, false
+}
+
+func publicKeyHex(key *Key) string {
+	return hexutil.Encode(crypto.FromECDSAPub(&key.PrivateKey.PublicKey)[1:])
+}
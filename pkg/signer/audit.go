@@ -0,0 +1,62 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// DataDigest is a raw 32 byte digest
+	DataDigest = "digest"
+	// DataEIP712Message is an encoded EIP-712 message of the eth1 sign
+	// endpoint
+	DataEIP712Message = "eip712_message"
+)
+
+// AuditEntry is one signing request, allowed or not
+type AuditEntry struct {
+	Time    time.Time      `json:"time"`
+	Remote  string         `json:"remote,omitempty"`
+	Method  string         `json:"method"`
+	KeyName string         `json:"key_name,omitempty"`
+	Address common.Address `json:"address"`
+	// Data is the primary type of the typed data signed, or DataDigest or
+	// DataEIP712Message
+	Data    string          `json:"data,omitempty"`
+	ChainID uint64          `json:"chain_id,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Hash    common.Hash     `json:"hash"`
+	Allowed bool            `json:"allowed"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// AuditLog writes one JSON line per signing request. A request is refused
+// when its entry can't be written
+type AuditLog struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+func NewAuditLog(writer io.Writer) *AuditLog {
+	return &AuditLog{writer: writer}
+}
+
+func (a *AuditLog) Record(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Error encoding audit entry: %w", err)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, err := a.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Error writing audit log: %w", err)
+	}
+	return nil
+}
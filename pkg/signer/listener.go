@@ -0,0 +1,116 @@
+package signer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const sniffTimeout = 10 * time.Second
+
+// Serve accepts connections until ctx is done. A connection that starts
+// with a JSON-RPC message is served as IPC, as geth clients do on a unix
+// socket, any other one is served as HTTP
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpConns := &connListener{addr: listener.Addr(), conns: make(chan net.Conn), done: make(chan struct{})}
+	httpServer := &http.Server{Handler: s, ReadHeaderTimeout: sniffTimeout}
+	go httpServer.Serve(httpConns)
+
+	stop := context.AfterFunc(ctx, func() {
+		listener.Close()
+	})
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), sniffTimeout)
+			httpServer.Shutdown(shutdownCtx)
+			cancel()
+			httpConns.Close()
+			s.rpc.Stop()
+
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("Error accepting connection: %w", err)
+		}
+		go s.serveConn(conn, httpConns)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn, httpConns *connListener) {
+	reader := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	first, err := reader.Peek(1)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	peeked := &peekedConn{Conn: conn, reader: reader}
+	if first[0] == '{' || first[0] == '[' {
+		s.rpc.ServeCodec(rpc.NewCodec(peeked), 0)
+		return
+	}
+
+	if !httpConns.push(peeked) {
+		conn.Close()
+	}
+}
+
+// peekedConn reads the bytes already peeked before the rest of the
+// connection
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// connListener hands the HTTP connections to the http server
+type connListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	once  sync.Once
+	done  chan struct{}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *connListener) push(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
@@ -0,0 +1,122 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// Policy is the allowlist of one key. Typed data is only signed for the
+// registration contracts of the chains listed, transactions for the chains
+// and the contracts listed, and raw digests with SignData
+type Policy struct {
+	SignData        bool             `json:"sign_data"`
+	SignTransaction bool             `json:"sign_transaction"`
+	To              []common.Address `json:"to"`
+	ChainIDs        []uint64         `json:"chain_ids"`
+}
+
+// DefaultPolicy lets a key sign the registration typed data and the
+// transactions of the witnesschain contracts of every known network. Raw
+// digests need sign_data in the allowlist
+func DefaultPolicy() (Policy, error) {
+	networks, err := wc_common.GetNetworks()
+	if err != nil {
+		return Policy{}, err
+	}
+
+	policy := Policy{SignTransaction: true}
+	for _, chainConfig := range networks {
+		policy.ChainIDs = append(policy.ChainIDs, chainConfig.ChainID.Uint64())
+		for _, address := range []common.Address{chainConfig.OperatorRegistryAddress, chainConfig.WitnessHubAddress, chainConfig.DiligenceProofManagerAddress} {
+			if address != (common.Address{}) {
+				policy.To = append(policy.To, address)
+			}
+		}
+	}
+	return policy, nil
+}
+
+// LoadAllowlist reads the policies of an allowlist file, keyed by key name.
+// A policy without contracts or chains gets the ones of DefaultPolicy
+func LoadAllowlist(path string) (map[string]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading allowlist file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var policies map[string]Policy
+	if err := decoder.Decode(&policies); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling allowlist file: %w", err)
+	}
+
+	defaults, err := DefaultPolicy()
+	if err != nil {
+		return nil, err
+	}
+	for keyName, policy := range policies {
+		if err := wc_common.ValidateKeyName(keyName); err != nil {
+			return nil, fmt.Errorf("Error validating key name of the allowlist: %w", err)
+		}
+		if len(policy.To) == 0 {
+			policy.To = defaults.To
+		}
+		if len(policy.ChainIDs) == 0 {
+			policy.ChainIDs = defaults.ChainIDs
+		}
+		policies[keyName] = policy
+	}
+	return policies, nil
+}
+
+// checkDigest allows a raw digest, which can be the hash of anything
+func (p Policy) checkDigest() error {
+	if !p.SignData {
+		return fmt.Errorf("raw digest, allow it with sign_data in the allowlist: %w", wc_common.ErrSigningDenied)
+	}
+	return nil
+}
+
+// checkTypedData checks the chain of typed data, its contract is checked by
+// the server against the network of the chain
+func (p Policy) checkTypedData(chainID *big.Int) error {
+	if !p.allowsChain(chainID) {
+		return fmt.Errorf("chain id %s: %w", chainID, wc_common.ErrSigningDenied)
+	}
+	return nil
+}
+
+func (p Policy) checkTransaction(chainID *big.Int, to *common.Address) error {
+	if !p.SignTransaction {
+		return fmt.Errorf("transaction: %w", wc_common.ErrSigningDenied)
+	}
+	if chainID == nil || !p.allowsChain(chainID) {
+		return fmt.Errorf("chain id %v: %w", chainID, wc_common.ErrSigningDenied)
+	}
+	if to == nil {
+		return fmt.Errorf("contract creation: %w", wc_common.ErrSigningDenied)
+	}
+	for _, address := range p.To {
+		if address == *to {
+			return nil
+		}
+	}
+	return fmt.Errorf("to %s: %w", to.Hex(), wc_common.ErrSigningDenied)
+}
+
+func (p Policy) allowsChain(chainID *big.Int) bool {
+	for _, id := range p.ChainIDs {
+		if new(big.Int).SetUint64(id).Cmp(chainID) == 0 {
+			return true
+		}
+	}
+	return false
+}
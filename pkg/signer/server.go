@@ -0,0 +1,349 @@
+// Package signer serves keys of the local keystore to watchtower hosts and
+// to the operator cli, so they can sign without holding key files. It
+// speaks the JSON-RPC API of clef, used by external_signer_endpoint, and
+// the eth1 HTTP API of Web3Signer
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+	"github.com/witnesschain-com/operator-cli/common/bindings/AvsDirectory"
+	"github.com/witnesschain-com/operator-cli/common/bindings/OperatorRegistry"
+)
+
+// Key is a key served by the signer with its allowlist
+type Key struct {
+	Name       string
+	PrivateKey *ecdsa.PrivateKey
+	Policy     Policy
+}
+
+// Chain is a chain served with --rpc-url, the domain separators of the
+// registration contracts of its network are read through Caller
+type Chain struct {
+	Config wc_common.ChainConfig
+	Caller bind.ContractCaller
+}
+
+type Server struct {
+	keys    map[common.Address]*Key
+	address []common.Address
+	chains  map[uint64]Chain
+	audit   *AuditLog
+	rpc     *rpc.Server
+	mux     *http.ServeMux
+}
+
+func NewServer(keys []*Key, chains []Chain, audit *AuditLog) (*Server, error) {
+	s := &Server{keys: map[common.Address]*Key{}, chains: map[uint64]Chain{}, audit: audit, rpc: rpc.NewServer(), mux: http.NewServeMux()}
+	for _, key := range keys {
+		address := crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+		s.keys[address] = key
+		s.address = append(s.address, address)
+	}
+	for _, chain := range chains {
+		s.chains[chain.Config.ChainID.Uint64()] = chain
+	}
+
+	if err := s.rpc.RegisterName("account", &accountAPI{server: s}); err != nil {
+		return nil, fmt.Errorf("Error registering account api: %w", err)
+	}
+	if err := s.rpc.RegisterName("eth", &ethAPI{server: s}); err != nil {
+		return nil, fmt.Errorf("Error registering eth api: %w", err)
+	}
+
+	s.mux.HandleFunc("GET /upcheck", s.handleUpcheck)
+	s.mux.HandleFunc("GET /api/v1/eth1/publicKeys", s.handlePublicKeys)
+	s.mux.HandleFunc("POST /api/v1/eth1/sign/{identifier}", s.handleSign)
+	s.mux.Handle("POST /", s.rpc)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Addresses returns the addresses of the keys served
+func (s *Server) Addresses() []common.Address {
+	return append([]common.Address{}, s.address...)
+}
+
+// signData signs data sent with the data/typed content type: EIP-712
+// typed data whose domain is a registration contract of an allowed chain,
+// or a raw 32 byte digest when the allowlist of the key has sign_data.
+// Personal messages and transactions are refused. The signature has V as
+// 27/28 like the ones of clef
+func (s *Server) signData(ctx context.Context, method string, address common.Address, contentType string, data []byte) ([]byte, error) {
+	entry := s.newEntry(ctx, method, address)
+
+	key, err := s.key(address)
+	if err == nil {
+		entry.KeyName = key.Name
+	}
+
+	var hash []byte
+	if err == nil {
+		switch {
+		case contentType != apitypes.DataTyped.Mime:
+			err = fmt.Errorf("content type %s, only %s is signed: %w", contentType, apitypes.DataTyped.Mime, wc_common.ErrSigningDenied)
+		case len(data) == common.HashLength:
+			entry.Data = DataDigest
+			hash, err = data, key.Policy.checkDigest()
+		case isTransaction(data):
+			err = fmt.Errorf("transaction data, use the transaction methods: %w", wc_common.ErrSigningDenied)
+		default:
+			var typedData apitypes.TypedData
+			if err = json.Unmarshal(data, &typedData); err != nil {
+				err = fmt.Errorf("data is not typed data: %w", wc_common.ErrSigningDenied)
+			} else {
+				hash, err = s.typedDataHash(key, typedData, &entry)
+			}
+		}
+	}
+	return s.signHash(key, hash, entry, err)
+}
+
+// signTypedData signs EIP-712 typed data whose domain is a registration
+// contract of an allowed chain
+func (s *Server) signTypedData(ctx context.Context, method string, address common.Address, typedData apitypes.TypedData) ([]byte, error) {
+	entry := s.newEntry(ctx, method, address)
+
+	key, err := s.key(address)
+	var hash []byte
+	if err == nil {
+		entry.KeyName = key.Name
+		hash, err = s.typedDataHash(key, typedData, &entry)
+	}
+	return s.signHash(key, hash, entry, err)
+}
+
+// signMessage signs the keccak256 hash of data, as the eth1 sign endpoint
+// of Web3Signer does. data must be an encoded EIP-712 message, 0x1901
+// followed by the domain separator and the struct hash, whose domain
+// separator is the one of a registration contract of a served chain
+func (s *Server) signMessage(ctx context.Context, method string, address common.Address, data []byte) ([]byte, error) {
+	entry := s.newEntry(ctx, method, address)
+
+	key, err := s.key(address)
+	var hash []byte
+	if err == nil {
+		entry.KeyName = key.Name
+		entry.Data = DataEIP712Message
+		if len(data) != 2+2*common.HashLength || data[0] != 0x19 || data[1] != 0x01 {
+			err = fmt.Errorf("data is not an encoded EIP-712 message: %w", wc_common.ErrSigningDenied)
+		} else {
+			hash = crypto.Keccak256(data)
+			err = s.checkDomainSeparator(ctx, key, common.BytesToHash(data[2:2+common.HashLength]), &entry)
+		}
+	}
+	return s.signHash(key, hash, entry, err)
+}
+
+// signHash records the audit entry of a request and signs hash when the
+// request is allowed
+func (s *Server) signHash(key *Key, hash []byte, entry AuditEntry, err error) ([]byte, error) {
+	if hash != nil {
+		entry.Hash = common.BytesToHash(hash)
+	}
+	if err := s.record(entry, err); err != nil {
+		return nil, err
+	}
+
+	signature, err := crypto.Sign(hash, key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Signing the hash failed: %w", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+func (s *Server) signTransaction(ctx context.Context, method string, args apitypes.SendTxArgs) (*types.Transaction, error) {
+	entry := s.newEntry(ctx, method, args.From.Address())
+
+	var chainID *big.Int
+	if args.ChainID != nil {
+		chainID = args.ChainID.ToInt()
+		entry.ChainID = chainID.Uint64()
+	}
+	if args.To != nil {
+		to := args.To.Address()
+		entry.To = &to
+	}
+
+	tx, err := args.ToTransaction()
+	if err != nil {
+		err = fmt.Errorf("Error reading transaction: %w", err)
+	}
+
+	var key *Key
+	if err == nil {
+		entry.Hash = types.LatestSignerForChainID(chainID).Hash(tx)
+		key, err = s.key(args.From.Address())
+	}
+	if err == nil {
+		entry.KeyName = key.Name
+		err = key.Policy.checkTransaction(chainID, entry.To)
+	}
+	if err := s.record(entry, err); err != nil {
+		return nil, err
+	}
+
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("Signing the transaction failed: %w", err)
+	}
+	return signedTx, nil
+}
+
+// typedDataHash checks that the domain of typedData is a registration
+// contract of a chain the key may sign on, and returns its EIP-712 hash
+func (s *Server) typedDataHash(key *Key, typedData apitypes.TypedData, entry *AuditEntry) ([]byte, error) {
+	entry.Data = typedData.PrimaryType
+
+	domain := typedData.Domain
+	if domain.ChainId == nil || !common.IsHexAddress(domain.VerifyingContract) {
+		return nil, fmt.Errorf("typed data without chain id or verifying contract: %w", wc_common.ErrSigningDenied)
+	}
+	chainID := (*big.Int)(domain.ChainId)
+	contract := common.HexToAddress(domain.VerifyingContract)
+	entry.ChainID = chainID.Uint64()
+	entry.To = &contract
+
+	if err := key.Policy.checkTypedData(chainID); err != nil {
+		return nil, err
+	}
+	chainConfig, err := s.chainConfig(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("chain id %s: %w", chainID, wc_common.ErrSigningDenied)
+	}
+	if !isRegistrationContract(chainConfig, contract) {
+		return nil, fmt.Errorf("verifying contract %s is not a registration contract: %w", contract.Hex(), wc_common.ErrSigningDenied)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("Error hashing typed data: %w", err)
+	}
+	return hash, nil
+}
+
+// checkDomainSeparator checks that separator is the domain separator of a
+// registration contract of a served chain the key may sign on
+func (s *Server) checkDomainSeparator(ctx context.Context, key *Key, separator common.Hash, entry *AuditEntry) error {
+	for chainID, chain := range s.chains {
+		if key.Policy.checkTypedData(new(big.Int).SetUint64(chainID)) != nil {
+			continue
+		}
+
+		separators, err := domainSeparators(ctx, chain)
+		if err != nil {
+			return err
+		}
+		for contract, domainSeparator := range separators {
+			if domainSeparator == separator {
+				entry.ChainID = chainID
+				entry.To = &contract
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("domain separator %s is not the one of a registration contract of a served chain: %w", separator.Hex(), wc_common.ErrSigningDenied)
+}
+
+// domainSeparators reads the domain separators of the registration
+// contracts of chain
+func domainSeparators(ctx context.Context, chain Chain) (map[common.Address]common.Hash, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	separators := map[common.Address]common.Hash{}
+
+	if address := chain.Config.OperatorRegistryAddress; address != (common.Address{}) {
+		operatorRegistry, err := OperatorRegistry.NewOperatorRegistryCaller(address, chain.Caller)
+		if err != nil {
+			return nil, fmt.Errorf("Instantiating OperatorRegistry contract failed: %w", err)
+		}
+		if separators[address], err = operatorRegistry.DomainSeperator(opts); err != nil {
+			return nil, fmt.Errorf("Error reading the domain separator of OperatorRegistry: %w", err)
+		}
+	}
+	if address := chain.Config.AVSDirectoryAddress; address != (common.Address{}) {
+		avsDirectory, err := AvsDirectory.NewAvsDirectoryCaller(address, chain.Caller)
+		if err != nil {
+			return nil, fmt.Errorf("Instantiating AvsDirectory contract failed: %w", err)
+		}
+		if separators[address], err = avsDirectory.DomainSeparator(opts); err != nil {
+			return nil, fmt.Errorf("Error reading the domain separator of AVSDirectory: %w", err)
+		}
+	}
+	return separators, nil
+}
+
+// chainConfig returns the network of a chain served with --rpc-url, or of
+// the known networks
+func (s *Server) chainConfig(chainID *big.Int) (wc_common.ChainConfig, error) {
+	if chain, ok := s.chains[chainID.Uint64()]; ok && chainID.IsUint64() {
+		return chain.Config, nil
+	}
+	return wc_common.GetChainConfig(chainID)
+}
+
+// isRegistrationContract reports whether contract is the OperatorRegistry
+// or the AVSDirectory of a network, the contracts registrations are signed
+// for
+func isRegistrationContract(chainConfig wc_common.ChainConfig, contract common.Address) bool {
+	if contract == (common.Address{}) {
+		return false
+	}
+	return contract == chainConfig.OperatorRegistryAddress || contract == chainConfig.AVSDirectoryAddress
+}
+
+// isTransaction reports whether data decodes as a legacy or a typed
+// transaction
+func isTransaction(data []byte) bool {
+	var tx types.Transaction
+	return tx.UnmarshalBinary(data) == nil
+}
+
+func (s *Server) key(address common.Address) (*Key, error) {
+	key, ok := s.keys[address]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", address.Hex(), wc_common.ErrUnknownSigningKey)
+	}
+	return key, nil
+}
+
+func (s *Server) newEntry(ctx context.Context, method string, address common.Address) AuditEntry {
+	entry := AuditEntry{Time: time.Now().UTC(), Method: method, Address: address}
+	if peer := rpc.PeerInfoFromContext(ctx); peer.RemoteAddr != "" {
+		entry.Remote = peer.RemoteAddr
+	} else if remote, ok := ctx.Value(remoteAddrKey{}).(string); ok {
+		entry.Remote = remote
+	}
+	return entry
+}
+
+// record writes the audit entry of a request and returns why it is refused,
+// a request that can't be audited is refused too
+func (s *Server) record(entry AuditEntry, err error) error {
+	entry.Allowed = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if auditErr := s.audit.Record(entry); auditErr != nil {
+		return auditErr
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	wc_common "github.com/witnesschain-com/operator-cli/common"
+)
+
+// separatorCaller answers every contract call with separator, as the
+// domainSeparator of the registration contracts
+type separatorCaller struct {
+	separator common.Hash
+	calls     int
+}
+
+func (c *separatorCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func (c *separatorCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	return c.separator.Bytes(), nil
+}
+
+func newTestServer(t *testing.T, policy Policy) (*Server, *Key, *separatorCaller, wc_common.ChainConfig) {
+	t.Helper()
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &Key{Name: "watchtower1", PrivateKey: privateKey, Policy: policy}
+
+	chainConfig := wc_common.ChainConfig{
+		OperatorRegistryAddress: common.HexToAddress("0x0000000000000000000000000000000000000a01"),
+		WitnessHubAddress:       common.HexToAddress("0x0000000000000000000000000000000000000a02"),
+		AVSDirectoryAddress:     common.HexToAddress("0x0000000000000000000000000000000000000a03"),
+	}
+	chainConfig.ChainID.SetUint64(17000)
+
+	caller := &separatorCaller{separator: crypto.Keccak256Hash([]byte("domain"))}
+	server, err := NewServer([]*Key{key}, []Chain{{Config: chainConfig, Caller: caller}}, NewAuditLog(&bytes.Buffer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, key, caller, chainConfig
+}
+
+// registrationTypedData is the EIP-712 typed data of an operator AVS
+// registration of AVSDirectory
+func registrationTypedData(chainID int64, contract common.Address, operator common.Address, avs common.Address) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"OperatorAVSRegistration": {
+				{Name: "operator", Type: "address"},
+				{Name: "avs", Type: "address"},
+				{Name: "salt", Type: "bytes32"},
+				{Name: "expiry", Type: "uint256"},
+			},
+		},
+		PrimaryType: "OperatorAVSRegistration",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "EigenLayer",
+			ChainId:           math.NewHexOrDecimal256(chainID),
+			VerifyingContract: contract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"operator": operator.Hex(),
+			"avs":      avs.Hex(),
+			"salt":     common.Hash{}.Hex(),
+			"expiry":   "1700000000",
+		},
+	}
+}
+
+func typedDataJSON(t *testing.T, typedData apitypes.TypedData) []byte {
+	t.Helper()
+	data, err := json.Marshal(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkSignature checks that signature, with V as 27/28, is of hash by key
+func checkSignature(t *testing.T, key *Key, hash []byte, signature []byte) {
+	t.Helper()
+	signature = append([]byte{}, signature...)
+	signature[crypto.RecoveryIDOffset] -= 27
+	publicKey, err := crypto.SigToPub(hash, signature)
+	if err != nil || crypto.PubkeyToAddress(*publicKey) != crypto.PubkeyToAddress(key.PrivateKey.PublicKey) {
+		t.Errorf("signature is not of the expected hash: %v", err)
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	policy, err := DefaultPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy.ChainIDs = []uint64{17000}
+	server, key, _, chainConfig := newTestServer(t, policy)
+	address := crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+
+	typedData := registrationTypedData(17000, chainConfig.AVSDirectoryAddress, address, chainConfig.WitnessHubAddress)
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the data/typed payload of account_signData, and the typed data
+	// methods
+	signature, err := server.signData(context.Background(), "account_signData", address, apitypes.DataTyped.Mime, typedDataJSON(t, typedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, key, hash, signature)
+
+	signature, err = server.signTypedData(context.Background(), "eth_signTypedData", address, typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, key, hash, signature)
+}
+
+func TestSignEIP712Message(t *testing.T) {
+	server, key, caller, _ := newTestServer(t, Policy{ChainIDs: []uint64{17000}})
+	address := crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+
+	// the eth1 sign endpoint signs the keccak256 hash of the message, which
+	// is the EIP-712 digest
+	message := append([]byte{0x19, 0x01}, caller.separator.Bytes()...)
+	message = append(message, crypto.Keccak256([]byte("registration"))...)
+	signature, err := server.signMessage(context.Background(), "eth1_sign", address, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, key, crypto.Keccak256(message), signature)
+
+	other := append([]byte{0x19, 0x01}, crypto.Keccak256([]byte("permit domain"))...)
+	other = append(other, crypto.Keccak256([]byte("permit"))...)
+	if _, err := server.signMessage(context.Background(), "eth1_sign", address, other); !errors.Is(err, wc_common.ErrSigningDenied) {
+		t.Errorf("message of another domain: error = %v, want ErrSigningDenied", err)
+	}
+}
+
+func TestSignDataRefused(t *testing.T) {
+	server, key, _, chainConfig := newTestServer(t, Policy{ChainIDs: []uint64{17000}})
+	address := crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+
+	tx, err := types.SignNewTx(key.PrivateKey, types.LatestSignerForChainID(big.NewInt(17000)), &types.DynamicFeeTx{
+		ChainID: big.NewInt(17000),
+		To:      &chainConfig.WitnessHubAddress,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawTx, _ := tx.MarshalBinary()
+	legacyTx, _ := types.NewTx(&types.LegacyTx{To: &chainConfig.WitnessHubAddress}).MarshalBinary()
+
+	otherContract := registrationTypedData(17000, chainConfig.WitnessHubAddress, address, chainConfig.WitnessHubAddress)
+	otherChain := registrationTypedData(1, chainConfig.AVSDirectoryAddress, address, chainConfig.WitnessHubAddress)
+	noDomain := registrationTypedData(17000, chainConfig.AVSDirectoryAddress, address, chainConfig.WitnessHubAddress)
+	noDomain.Domain.ChainId = nil
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"raw hash without sign_data", apitypes.DataTyped.Mime, crypto.Keccak256([]byte("anything"))},
+		{"typed transaction", apitypes.DataTyped.Mime, rawTx},
+		{"legacy transaction", apitypes.DataTyped.Mime, legacyTx},
+		{"personal message", accounts.MimetypeTextPlain, []byte("hello")},
+		{"not typed data", apitypes.DataTyped.Mime, []byte("hello")},
+		{"other contract", apitypes.DataTyped.Mime, typedDataJSON(t, otherContract)},
+		{"chain not allowed", apitypes.DataTyped.Mime, typedDataJSON(t, otherChain)},
+		{"no chain id", apitypes.DataTyped.Mime, typedDataJSON(t, noDomain)},
+	}
+	for _, test := range tests {
+		_, err := server.signData(context.Background(), "account_signData", address, test.contentType, test.data)
+		if !errors.Is(err, wc_common.ErrSigningDenied) {
+			t.Errorf("%s: error = %v, want ErrSigningDenied", test.name, err)
+		}
+	}
+
+	for name, data := range map[string][]byte{"raw hash": crypto.Keccak256([]byte("anything")), "transaction": rawTx} {
+		if _, err := server.signMessage(context.Background(), "eth1_sign", address, data); !errors.Is(err, wc_common.ErrSigningDenied) {
+			t.Errorf("eth1 %s: error = %v, want ErrSigningDenied", name, err)
+		}
+	}
+}
+
+func TestSignDigestNeedsAllowlist(t *testing.T) {
+	policy, err := DefaultPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.SignData {
+		t.Fatal("the default policy signs raw digests")
+	}
+
+	digest := crypto.Keccak256([]byte("registration"))
+	server, key, _, _ := newTestServer(t, policy)
+	address := crypto.PubkeyToAddress(key.PrivateKey.PublicKey)
+	if _, err := server.signData(context.Background(), "account_signData", address, apitypes.DataTyped.Mime, digest); !errors.Is(err, wc_common.ErrSigningDenied) {
+		t.Errorf("error = %v, want ErrSigningDenied", err)
+	}
+
+	key.Policy.SignData = true
+	signature, err := server.signData(context.Background(), "account_signData", address, apitypes.DataTyped.Mime, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, key, digest, signature)
+}